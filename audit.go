@@ -0,0 +1,242 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/icanhazstring/sshlink/logging"
+)
+
+// auditRecord is one line of the audit log, written for every link handled
+type auditRecord struct {
+	Time     time.Time `json:"time"`
+	URL      string    `json:"url"`
+	Target   string    `json:"target,omitempty"`
+	Host     string    `json:"host,omitempty"`
+	Decision string    `json:"decision"` // allowed or rejected
+	Reason   string    `json:"reason,omitempty"`
+	Terminal string    `json:"terminal,omitempty"`
+	Outcome  string    `json:"outcome"` // opened, failed or rejected
+	Error    string    `json:"error,omitempty"`
+}
+
+// redactedValue replaces secrets in audited URLs
+const redactedValue = "REDACTED"
+
+// secretParams are query parameter names whose values never reach the audit log
+var secretParams = []string{"token", "password", "passwd", "secret", "key", "auth"}
+
+func auditPath() string {
+	dir := stateDir()
+	if dir == "" {
+		return ""
+	}
+	return filepath.Join(dir, "audit.jsonl")
+}
+
+// redactURL drops the userinfo password and the values of secret-looking
+// query parameters, keeping everything else as the link was written
+func redactURL(urlString string) string {
+	u, err := url.Parse(urlString)
+	if err != nil {
+		return redactInvalidURL(urlString)
+	}
+
+	if _, ok := u.User.Password(); ok {
+		u.User = url.UserPassword(u.User.Username(), redactedValue)
+	}
+	u.RawQuery = redactQuery(u.RawQuery)
+	return u.String()
+}
+
+// redactInvalidURL keeps a link that doesn't parse as evidence, with any
+// user:pass@ part and secret parameter values removed
+func redactInvalidURL(urlString string) string {
+	rest, query, hasQuery := strings.Cut(urlString, "?")
+	if scheme, authority, ok := strings.Cut(rest, "://"); ok {
+		host, path, hasPath := strings.Cut(authority, "/")
+		if at := strings.LastIndex(host, "@"); at >= 0 && strings.Contains(host[:at], ":") {
+			host = host[at+1:]
+		}
+		rest = scheme + "://" + host
+		if hasPath {
+			rest += "/" + path
+		}
+	}
+	if hasQuery {
+		rest += "?" + redactQuery(query)
+	}
+	return fmt.Sprintf("(invalid URL, %d bytes) %s", len(urlString), rest)
+}
+
+// redactQuery replaces the values of secret-looking parameters
+func redactQuery(rawQuery string) string {
+	if rawQuery == "" {
+		return ""
+	}
+	params := strings.Split(rawQuery, "&")
+	for i, param := range params {
+		name, _, _ := strings.Cut(param, "=")
+		if decoded, err := url.QueryUnescape(name); err == nil {
+			name = decoded
+		}
+		if isSecretParam(name) {
+			params[i] = url.QueryEscape(name) + "=" + redactedValue
+		}
+	}
+	return strings.Join(params, "&")
+}
+
+func isSecretParam(name string) bool {
+	name = strings.ToLower(name)
+	for _, secret := range secretParams {
+		if strings.Contains(name, secret) {
+			return true
+		}
+	}
+	return false
+}
+
+// writeAudit appends record to the audit log. Failing to audit is logged
+// but doesn't stop the link from opening.
+func writeAudit(record auditRecord) {
+	auditFile := auditPath()
+	if auditFile == "" {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(auditFile), 0700); err != nil {
+		logging.Warnf("Failed to create audit directory: %v", err)
+		return
+	}
+
+	line, err := json.Marshal(record)
+	if err != nil {
+		logging.Warnf("Failed to encode audit record: %v", err)
+		return
+	}
+
+	file, err := os.OpenFile(auditFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		logging.Warnf("Failed to open audit log: %v", err)
+		return
+	}
+	defer file.Close()
+
+	// A single write keeps concurrent handlers from interleaving records
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		logging.Warnf("Failed to write audit record: %v", err)
+	}
+}
+
+// readAudit returns every record in the audit log, oldest first
+func readAudit() ([]auditRecord, error) {
+	file, err := os.Open(auditPath())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var records []auditRecord
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var record auditRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			logging.Warnf("Skipping malformed audit record: %v", err)
+			continue
+		}
+		records = append(records, record)
+	}
+	return records, scanner.Err()
+}
+
+func runAudit(args []string) error {
+	usage := func() {
+		fmt.Fprintf(os.Stderr, "Usage: sshlink audit tail [-n 20] [-json]\n       sshlink audit search -host <pattern> [-json]\n")
+	}
+	if len(args) == 0 {
+		usage()
+		return fmt.Errorf("expected tail or search")
+	}
+
+	fs := flag.NewFlagSet("audit "+args[0], flag.ExitOnError)
+	asJSON := fs.Bool("json", false, "Print raw JSON records")
+	fs.Usage = func() {
+		usage()
+		fmt.Fprintf(fs.Output(), "\nOptions:\n")
+		fs.PrintDefaults()
+	}
+
+	switch args[0] {
+	case "tail":
+		count := fs.Int("n", 20, "Number of records to show")
+		fs.Parse(args[1:])
+
+		records, err := readAudit()
+		if err != nil {
+			return fmt.Errorf("failed to read audit log: %v", err)
+		}
+		if *count >= 0 && len(records) > *count {
+			records = records[len(records)-*count:]
+		}
+		return printAudit(records, *asJSON)
+	case "search":
+		host := fs.String("host", "", "Host or pattern (e.g. prod-*) to search for")
+		fs.Parse(args[1:])
+		if *host == "" {
+			fs.Usage()
+			return fmt.Errorf("-host is required")
+		}
+
+		records, err := readAudit()
+		if err != nil {
+			return fmt.Errorf("failed to read audit log: %v", err)
+		}
+		var matches []auditRecord
+		for _, record := range records {
+			if matched, _ := path.Match(*host, record.Host); matched {
+				matches = append(matches, record)
+			}
+		}
+		return printAudit(matches, *asJSON)
+	default:
+		usage()
+		return fmt.Errorf("unknown audit command: %s", args[0])
+	}
+}
+
+func printAudit(records []auditRecord, asJSON bool) error {
+	if asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		for _, record := range records {
+			if err := encoder.Encode(record); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if len(records) == 0 {
+		fmt.Println("No audit records")
+		return nil
+	}
+	for _, record := range records {
+		line := fmt.Sprintf("%s  %-8s  %-8s  %s", record.Time.Local().Format("2006-01-02 15:04:05"), record.Outcome, record.Terminal, record.URL)
+		if record.Error != "" {
+			line += "  (" + record.Error + ")"
+		}
+		fmt.Println(line)
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/icanhazstring/sshlink/sshconfig"
+)
+
+// subcommands are dispatched on the first positional argument,
+// e.g. "sshlink resolve sshlink://prod-db-1"
+var subcommands = map[string]func(args []string) error{
+	"resolve":     runResolve,
+	"gen":         runGen,
+	"page":        runPage,
+	"serve":       runServe,
+	"copy":        runCopy,
+	"tunnels":     runTunnels,
+	"audit":       runAudit,
+	"recent":      runRecent,
+	"reopen":      runReopen,
+	"fav":         runFav,
+	"pick":        runPick,
+	"doctor":      runDoctor,
+	"open":        runOpen,
+	"install":     runInstall,
+	"uninstall":   runUninstall,
+	"status":      runStatus,
+	"upgrade":     runUpgrade,
+	"reinstall":   runReinstall,
+	"native-host": runNativeHost,
+}
+
+func runResolve(args []string) error {
+	fs := flag.NewFlagSet("resolve", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: sshlink resolve <sshlink://host>\n")
+	}
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return fmt.Errorf("expected exactly one URL")
+	}
+
+	u, err := url.Parse(fs.Arg(0))
+	if err != nil {
+		return fmt.Errorf("invalid URL: %v", err)
+	}
+	if u.Host == "" {
+		return fmt.Errorf("no target specified")
+	}
+
+	cfg := loadSSHConfig()
+	target := applySSHConfig(parseTarget(u), cfg)
+	resolved := resolveTarget(target, cfg)
+
+	fmt.Printf("Link target: %s\n", target)
+	if cfg.HasHost(target.Host) {
+		fmt.Printf("ssh_config:  Host %s (%s)\n", target.Host, sshconfig.DefaultPath())
+	} else {
+		fmt.Printf("ssh_config:  no Host alias for %s\n", target.Host)
+	}
+	fmt.Printf("HostName:    %s\n", resolved.Host)
+	if resolved.User != "" {
+		fmt.Printf("User:        %s\n", resolved.User)
+	} else {
+		fmt.Printf("User:        (local user)\n")
+	}
+	fmt.Printf("Port:        %s\n", resolved.Port)
+
+	settings := cfg.Resolve(target.Host, target.User)
+	for _, key := range []string{"ProxyJump", "ProxyCommand", "IdentityFile"} {
+		if value := settings[strings.ToLower(key)]; value != "" {
+			fmt.Printf("%-13s%s\n", key+":", value)
+		}
+	}
+	return nil
+}
+
+// parseInterspersed parses flags that may appear before, between or after
+// positional arguments, e.g. "fav add <url> -tag prod", and returns the
+// positional arguments
+func parseInterspersed(fs *flag.FlagSet, args []string) []string {
+	var positional []string
+	for {
+		fs.Parse(args)
+		if fs.NArg() == 0 {
+			return positional
+		}
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+}
+
+// stringList is a flag that may be repeated, values may also be comma-separated
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			*l = append(*l, v)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// configPath returns the path of the key=value config file
+func configPath() string {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(homeDir, ".config", "sshlink", "config")
+}
+
+// stateDir returns the directory for runtime state such as tunnels,
+// following $XDG_STATE_HOME (default ~/.local/state)
+func stateDir() string {
+	if dir := os.Getenv("XDG_STATE_HOME"); dir != "" {
+		return filepath.Join(dir, "sshlink")
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(homeDir, ".local", "state", "sshlink")
+}
+
+// systemConfigDir holds the system-wide config and policy written by
+// "sshlink install -system"
+var systemConfigDir = "/etc/sshlink"
+
+func systemConfigPath() string {
+	return filepath.Join(systemConfigDir, "config")
+}
+
+// configEntry is one key=value line of a config file
+type configEntry struct {
+	key   string
+	value string
+	line  int
+}
+
+// parseConfigFile reads key=value lines, skipping blank lines and # comments.
+// A missing file has no entries. Malformed lines are skipped and reported
+// in the error, so callers can choose to ignore them.
+func parseConfigFile(path string) ([]configEntry, error) {
+	content, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []configEntry
+	var malformed []string
+	for i, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			malformed = append(malformed, fmt.Sprintf("line %d", i+1))
+			continue
+		}
+		entries = append(entries, configEntry{key: key, value: value, line: i + 1})
+	}
+	if len(malformed) > 0 {
+		return entries, fmt.Errorf("%s: not key=value: %s", path, strings.Join(malformed, ", "))
+	}
+	return entries, nil
+}
+
+// readConfigValues returns every value set for key, in file order. Values
+// from the user's config come before those from the system config, so
+// the user's win where only the first value is used.
+func readConfigValues(key string) []string {
+	var values []string
+	for _, path := range []string{configPath(), systemConfigPath()} {
+		if path == "" {
+			continue
+		}
+		// Keys may repeat
+		entries, _ := parseConfigFile(path)
+		for _, entry := range entries {
+			if entry.key == key {
+				values = append(values, entry.value)
+			}
+		}
+	}
+	return values
+}
+
+// readConfigValue returns the first value set for key, or "" if unset
+func readConfigValue(key string) string {
+	values := readConfigValues(key)
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+// readPatternValue looks up key entries of the form "<host pattern>:<value>"
+// and returns the value of the first pattern matching host
+func readPatternValue(key, host string) string {
+	for _, entry := range readConfigValues(key) {
+		pattern, value, ok := strings.Cut(entry, ":")
+		if !ok {
+			continue
+		}
+		if matched, _ := path.Match(strings.TrimSpace(pattern), host); matched {
+			return strings.TrimSpace(value)
+		}
+	}
+	return ""
+}
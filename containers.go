@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// containerShells are the shells a link may ask for with ?shell=
+var containerShells = map[string]bool{"sh": true, "bash": true, "ash": true, "zsh": true}
+
+// isContainerLink reports whether an sshlink:// URL addresses a pod or
+// container (sshlink://k8s/... or sshlink://docker/...) rather than a host
+func isContainerLink(u *url.URL) bool {
+	return (u.Host == "k8s" || u.Host == "docker") && strings.Trim(u.EscapedPath(), "/") != ""
+}
+
+// containerSession builds the kubectl/docker exec session for a container link:
+//
+//	sshlink://k8s/<context>/<namespace>/<pod>?container=app&shell=bash
+//	sshlink://docker/<container>?shell=bash
+func containerSession(u *url.URL) (session, error) {
+	var segments []string
+	for _, segment := range strings.Split(strings.Trim(u.EscapedPath(), "/"), "/") {
+		unescaped, err := url.PathUnescape(segment)
+		if err != nil {
+			return session{}, fmt.Errorf("invalid path segment %q: %v", segment, err)
+		}
+		segments = append(segments, unescaped)
+	}
+
+	query := u.Query()
+	shell := query.Get("shell")
+	if shell == "" {
+		shell = "sh"
+	}
+	if !containerShells[shell] {
+		return session{}, fmt.Errorf("unsupported shell: %s", shell)
+	}
+
+	switch u.Host {
+	case "k8s":
+		if len(segments) != 3 {
+			return session{}, fmt.Errorf("expected sshlink://k8s/<context>/<namespace>/<pod>")
+		}
+		context, namespace, pod := segments[0], segments[1], segments[2]
+		for _, v := range []struct{ kind, value string }{
+			{"context", context}, {"namespace", namespace}, {"pod", pod}, {"container", query.Get("container")},
+		} {
+			if err := validateName(v.kind, v.value); err != nil {
+				return session{}, err
+			}
+		}
+
+		argv := []string{"kubectl", "--context", context, "--namespace", namespace, "exec", "-it", pod}
+		name := fmt.Sprintf("%s/%s/%s", context, namespace, pod)
+		if container := query.Get("container"); container != "" {
+			argv = append(argv, "-c", container)
+			name += "/" + container
+		}
+		argv = append(argv, "--", shell)
+
+		// Profiles match on the context so production clusters stand out
+		return session{label: "k8s", name: name, target: Target{Host: context}, command: argv}, nil
+
+	default:
+		if len(segments) != 1 {
+			return session{}, fmt.Errorf("expected sshlink://docker/<container>")
+		}
+		container := segments[0]
+		if err := validateName("container", container); err != nil {
+			return session{}, err
+		}
+		argv := []string{"docker", "exec", "-it", container, shell}
+		return session{label: "docker", name: container, target: Target{Host: container}, command: argv}, nil
+	}
+}
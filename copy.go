@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// runCopy is the interactive helper opened by scplink:// links. It runs
+// inside the new terminal, asks what to copy and hands off to rsync or scp.
+func runCopy(args []string) error {
+	fs := flag.NewFlagSet("copy", flag.ExitOnError)
+	port := fs.String("p", "", "ssh port of the remote host")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: sshlink copy [-p port] <user@host>\n")
+	}
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return fmt.Errorf("expected exactly one destination")
+	}
+	destination := fs.Arg(0)
+
+	fmt.Printf("📁 Copy files with %s\n", destination)
+	in := bufio.NewReader(os.Stdin)
+	direction := prompt(in, "Direction, [d]ownload from or [u]pload to remote", "d")
+	remotePath := prompt(in, "Remote path", "~")
+	localPath := prompt(in, "Local path", ".")
+
+	upload := strings.HasPrefix(strings.ToLower(direction), "u")
+	_, rsyncErr := exec.LookPath("rsync")
+	argv := copyCommand(rsyncErr == nil, upload, destination, *port, remotePath, localPath)
+
+	fmt.Printf("🚀 Running: %s\n", strings.Join(argv, " "))
+	cmd := exec.Command(argv[0], argv[1:]...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	return cmd.Run()
+}
+
+// copyCommand builds the rsync (preferred) or scp invocation for one transfer
+func copyCommand(rsync, upload bool, destination, port, remotePath, localPath string) []string {
+	remote := bracketIPv6(destination) + ":" + remotePath
+
+	var argv []string
+	if rsync {
+		argv = []string{"rsync", "-avP"}
+		if port != "" {
+			argv = append(argv, "-e", "ssh -p "+port)
+		}
+	} else {
+		argv = []string{"scp", "-r"}
+		if port != "" {
+			argv = append(argv, "-P", port)
+		}
+	}
+
+	if upload {
+		return append(argv, localPath, remote)
+	}
+	return append(argv, remote, localPath)
+}
+
+func prompt(in *bufio.Reader, question, fallback string) string {
+	fmt.Printf("%s [%s]: ", question, fallback)
+	answer, err := in.ReadString('\n')
+	if err != nil && err != io.EOF {
+		return fallback
+	}
+	if answer = strings.TrimSpace(answer); answer == "" {
+		return fallback
+	}
+	return answer
+}
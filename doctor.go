@@ -0,0 +1,342 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/icanhazstring/sshlink/logging"
+	"github.com/icanhazstring/sshlink/sshconfig"
+	"github.com/icanhazstring/sshlink/terminals"
+)
+
+// checkResult is one line of the doctor report
+type checkResult struct {
+	Name   string `json:"name"`
+	Status string `json:"status"` // pass, warn or fail
+	Detail string `json:"detail,omitempty"`
+	Fix    string `json:"fix,omitempty"`
+}
+
+func pass(name, detail string) checkResult {
+	return checkResult{Name: name, Status: "pass", Detail: detail}
+}
+
+func warn(name, detail, fix string) checkResult {
+	return checkResult{Name: name, Status: "warn", Detail: detail, Fix: fix}
+}
+
+func fail(name, detail, fix string) checkResult {
+	return checkResult{Name: name, Status: "fail", Detail: detail, Fix: fix}
+}
+
+// knownConfigKeys are the keys read from ~/.config/sshlink/config
+var knownConfigKeys = []string{
+	"terminal", "shell", "profile", "transport", "preflight", "preflight_timeout",
+	"tunnel_mode", "notify", "log_level", "serve_origin", "dry_run",
+	"deny_host", "allow_host", "disable_scheme", "allow_remote_command", "allow_forward",
+}
+
+func runDoctor(args []string) error {
+	fs := flag.NewFlagSet("doctor", flag.ExitOnError)
+	asJSON := fs.Bool("json", false, "Print the report as JSON")
+	terminal := fs.String("terminal", "terminal", "Terminal to check")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: sshlink doctor [options]\n\nOptions:\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	results := doctorChecks(resolveTerminalType(*terminal))
+
+	failed := 0
+	for _, r := range results {
+		if r.Status == "fail" {
+			failed++
+		}
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(results); err != nil {
+			return err
+		}
+	} else {
+		icons := map[string]string{"pass": "✅", "warn": "⚠️ ", "fail": "❌"}
+		for _, r := range results {
+			line := fmt.Sprintf("%s %s", icons[r.Status], r.Name)
+			if r.Detail != "" {
+				line += ": " + r.Detail
+			}
+			fmt.Println(line)
+			if r.Fix != "" {
+				fmt.Printf("   Fix: %s\n", r.Fix)
+			}
+		}
+		fmt.Printf("\nLogs: %s\n", logPath())
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d check(s) failed", failed)
+	}
+	return nil
+}
+
+func doctorChecks(terminalType string) []checkResult {
+	var results []checkResult
+	switch runtime.GOOS {
+	case "linux":
+		results = append(results, checkHandlerLinux()...)
+	case "darwin":
+		results = append(results, checkHandlerMacOS()...)
+	default:
+		results = append(results, warn("Handler registered", "not checked on "+runtime.GOOS, ""))
+	}
+	results = append(results,
+		checkTerminal(terminalType),
+		checkSSH(),
+		checkConfig(configPath()),
+		checkPolicy(),
+		checkSSHConfig(sshconfig.DefaultPath()),
+	)
+	if result, found := checkLegacyLog(legacyLogPath()); found {
+		results = append(results, result)
+	}
+	return results
+}
+
+// checkLegacyLog reports the debug log older versions wrote. It is the
+// user's file, so doctor only points it out.
+func checkLegacyLog(path string) (checkResult, bool) {
+	if path == "" {
+		return checkResult{}, false
+	}
+	if _, err := os.Stat(path); err != nil {
+		return checkResult{}, false
+	}
+	return warn("Legacy debug log", path+" may contain links you opened", "rm "+path), true
+}
+
+func checkHandlerLinux() []checkResult {
+	var results []checkResult
+	fix := `run "sshlink -install"`
+
+	for _, scheme := range schemeNames() {
+		name := fmt.Sprintf("Handler registered for %s://", scheme)
+		output, err := commandOutput("xdg-mime", "query", "default", "x-scheme-handler/"+scheme)
+		if err != nil {
+			results = append(results, fail(name, fmt.Sprintf("xdg-mime query failed: %v", err), "install xdg-utils, then "+fix))
+			continue
+		}
+		switch handler := strings.TrimSpace(string(output)); handler {
+		case "sshlink.desktop":
+			results = append(results, pass(name, handler))
+		case "":
+			results = append(results, fail(name, "no handler", fix))
+		default:
+			results = append(results, fail(name, "handled by "+handler, fix))
+		}
+	}
+
+	desktopFile := findInstallation().desktopFile
+	if desktopFile == "" {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return append(results, fail("Desktop file", err.Error(), ""))
+		}
+		desktopFile = linuxDesktopFile(homeDir)
+	}
+	return append(results, checkDesktopFile(desktopFile))
+}
+
+// checkDesktopFile verifies that the Exec= line of the desktop file
+// points at a binary that still exists, e.g. after moving sshlink
+func checkDesktopFile(desktopFile string) checkResult {
+	const name = "Desktop file Exec path"
+	content, err := os.ReadFile(desktopFile)
+	if err != nil {
+		return fail(name, fmt.Sprintf("cannot read %s", desktopFile), `run "sshlink -install"`)
+	}
+
+	for _, line := range strings.Split(string(content), "\n") {
+		value, ok := strings.CutPrefix(strings.TrimSpace(line), "Exec=")
+		if !ok {
+			continue
+		}
+		execPath := desktopExecPath(value)
+		info, err := os.Stat(execPath)
+		if err != nil {
+			return fail(name, fmt.Sprintf("%s does not exist", execPath), `re-run "sshlink -install" from the binary's new location`)
+		}
+		if info.IsDir() || info.Mode().Perm()&0111 == 0 {
+			return fail(name, fmt.Sprintf("%s is not executable", execPath), "chmod +x "+execPath)
+		}
+		return pass(name, execPath)
+	}
+	return fail(name, fmt.Sprintf("no Exec= line in %s", desktopFile), `run "sshlink -install"`)
+}
+
+// desktopExecPath returns the program of a desktop Exec= value, which may be quoted
+func desktopExecPath(value string) string {
+	value = strings.TrimSpace(value)
+	if rest, ok := strings.CutPrefix(value, `"`); ok {
+		if end := strings.Index(rest, `"`); end >= 0 {
+			return rest[:end]
+		}
+	}
+	program, _, _ := strings.Cut(value, " ")
+	return program
+}
+
+func checkHandlerMacOS() []checkResult {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return []checkResult{fail("App bundle", err.Error(), "")}
+	}
+	appPath := filepath.Join(homeDir, "Applications", "SSHLink.app")
+	fix := `run "sshlink -install"`
+
+	var results []checkResult
+	realExecPath := filepath.Join(appPath, "Contents", "MacOS", "SSHLink-real")
+	if _, err := os.Stat(realExecPath); err != nil {
+		results = append(results, fail("App bundle", fmt.Sprintf("%s is missing", realExecPath), fix))
+	} else {
+		results = append(results, pass("App bundle", appPath))
+	}
+
+	output, err := commandOutput(lsregisterPath, "-dump")
+	if err != nil {
+		return append(results, warn("Handler registered", fmt.Sprintf("lsregister -dump failed: %v", err), fix))
+	}
+	dump := string(output)
+	if !strings.Contains(dump, appPath) {
+		return append(results, fail("Handler registered", "SSHLink.app is not known to Launch Services", fix))
+	}
+	for _, scheme := range schemeNames() {
+		name := fmt.Sprintf("Handler registered for %s://", scheme)
+		if strings.Contains(dump, scheme+":") {
+			results = append(results, pass(name, "Launch Services"))
+		} else {
+			results = append(results, fail(name, "scheme not registered", fix))
+		}
+	}
+	return results
+}
+
+func checkTerminal(terminalType string) checkResult {
+	name := "Terminal " + terminalType
+	if runtime.GOOS == "linux" {
+		terminals.SetUserShell(readShellPreference())
+	}
+	terminal, err := terminals.CreateTerminal(terminalType)
+	if err != nil {
+		return fail(name, err.Error(), `run "sshlink -list" and re-install with a supported -terminal`)
+	}
+	if !terminal.IsAvailable() {
+		return fail(name, terminal.Name()+" is not installed", "install it or re-install sshlink with another -terminal")
+	}
+	return pass(name, terminal.Name()+" is available")
+}
+
+func checkSSH() checkResult {
+	path, err := lookPath("ssh")
+	if err != nil {
+		return fail("ssh on PATH", "ssh not found", "install an OpenSSH client")
+	}
+	return pass("ssh on PATH", path)
+}
+
+// checkConfig reports lines that aren't key=value, unknown keys and
+// values that sshlink would silently ignore
+func checkConfig(prefsFile string) checkResult {
+	const name = "Config"
+	content, err := os.ReadFile(prefsFile)
+	if os.IsNotExist(err) {
+		return pass(name, "no config file, using defaults")
+	}
+	if err != nil {
+		return fail(name, err.Error(), "")
+	}
+
+	var problems, unknown []string
+	for i, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			problems = append(problems, fmt.Sprintf("line %d is not key=value", i+1))
+			continue
+		}
+
+		switch key {
+		case "log_level":
+			if _, err := logging.ParseLevel(value); err != nil {
+				problems = append(problems, fmt.Sprintf("line %d: %v", i+1, err))
+			}
+		case "preflight_timeout":
+			if _, err := time.ParseDuration(value); err != nil {
+				problems = append(problems, fmt.Sprintf("line %d: invalid duration %q", i+1, value))
+			}
+		case "dry_run", "allow_remote_command", "allow_forward":
+			if _, err := parseSwitch(value); err != nil {
+				problems = append(problems, fmt.Sprintf("line %d: %s: %v", i+1, key, err))
+			}
+		case "profile":
+			if !strings.Contains(value, ":") {
+				problems = append(problems, fmt.Sprintf("line %d: expected profile=<host pattern>:<profile>", i+1))
+			}
+		default:
+			if !isKnownConfigKey(key) {
+				unknown = append(unknown, key)
+			}
+		}
+	}
+
+	if len(problems) > 0 {
+		return fail(name, prefsFile+": "+strings.Join(problems, "; "), "fix or remove the listed lines")
+	}
+	if len(unknown) > 0 {
+		return warn(name, "unknown keys: "+strings.Join(unknown, ", "), "check for typos, unknown keys are ignored")
+	}
+	return pass(name, prefsFile)
+}
+
+func isKnownConfigKey(key string) bool {
+	for _, known := range knownConfigKeys {
+		if key == known {
+			return true
+		}
+	}
+	return false
+}
+
+func checkPolicy() checkResult {
+	p, err := loadPolicy()
+	if err != nil {
+		return fail("Policy", err.Error()+", every link is refused", "fix the file named above")
+	}
+	detail := "no restrictions"
+	if _, err := os.Stat(systemPolicyPath()); err == nil {
+		detail = systemPolicyPath()
+	}
+	if len(p.denyHosts) > 0 || len(p.allowHosts) > 0 || len(p.disabledSchemes) > 0 || p.remoteCommandOff || !p.allowForward {
+		detail += fmt.Sprintf(" (%d denied, %d allowlists, %d schemes disabled)", len(p.denyHosts), len(p.allowHosts), len(p.disabledSchemes))
+	}
+	return pass("Policy", detail)
+}
+
+func checkSSHConfig(path string) checkResult {
+	cfg, err := sshconfig.Load(path)
+	if err != nil {
+		return fail("ssh_config", err.Error(), "fix "+path)
+	}
+	return pass("ssh_config", fmt.Sprintf("%d host aliases", len(cfg.Hosts())))
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestCheckConfig(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name     string
+		content  string
+		expected string
+	}{
+		{name: "Valid", content: "# comment\nterminal=iterm\nprofile=prod-*:Production\nlog_level=debug\n", expected: "pass"},
+		{name: "Unknown key", content: "termnial=iterm\n", expected: "warn"},
+		{name: "Not key=value", content: "terminal iterm\n", expected: "fail"},
+		{name: "Bad log level", content: "log_level=loud\n", expected: "fail"},
+		{name: "Bad timeout", content: "preflight_timeout=3\n", expected: "fail"},
+		{name: "Profile without pattern", content: "profile=Production\n", expected: "fail"},
+		{name: "Dry run", content: "dry_run=on\n", expected: "pass"},
+		{name: "Bad dry run", content: "dry_run=maybe\n", expected: "fail"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, "config")
+			os.WriteFile(path, []byte(tt.content), 0644)
+			if result := checkConfig(path); result.Status != tt.expected {
+				t.Errorf("Expected %s, got %+v", tt.expected, result)
+			}
+		})
+	}
+
+	if result := checkConfig(filepath.Join(dir, "missing")); result.Status != "pass" {
+		t.Errorf("Expected a missing config to pass, got %+v", result)
+	}
+}
+
+func TestCheckDesktopFile(t *testing.T) {
+	dir := t.TempDir()
+	binary := filepath.Join(dir, "sshlink")
+	os.WriteFile(binary, []byte("#!/bin/sh\n"), 0755)
+	desktopFile := filepath.Join(dir, "sshlink.desktop")
+
+	os.WriteFile(desktopFile, []byte("[Desktop Entry]\nExec="+binary+" -terminal=gnome-terminal %u\n"), 0644)
+	if result := checkDesktopFile(desktopFile); result.Status != "pass" {
+		t.Errorf("Expected pass, got %+v", result)
+	}
+
+	os.WriteFile(desktopFile, []byte("[Desktop Entry]\nExec=\""+binary+".old\" %u\n"), 0644)
+	if result := checkDesktopFile(desktopFile); result.Status != "fail" {
+		t.Errorf("Expected a moved binary to fail, got %+v", result)
+	}
+
+	if got := desktopExecPath(`"/opt/my apps/sshlink" -terminal=x %u`); got != "/opt/my apps/sshlink" {
+		t.Errorf("Expected quoted path, got %q", got)
+	}
+}
+
+func TestCheckSystemOnlyInstall(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("desktop entries are Linux only")
+	}
+	layout := systemLayout{prefix: systemPrefixes[0]}
+	writeTestFile(t, layout.binary(), "#!/bin/sh\n")
+	os.Chmod(layout.binary(), 0755)
+	writeTestFile(t, layout.desktopFile(), "[Desktop Entry]\nExec="+layout.binary()+" -terminal=gnome-terminal %u\n")
+
+	results := checkHandlerLinux()
+	if result := results[len(results)-1]; result.Status != "pass" || result.Detail != layout.binary() {
+		t.Errorf("Expected the system desktop file to pass, got %+v", result)
+	}
+}
+
+func TestLegacyLog(t *testing.T) {
+	path := legacyLogPath()
+	writeTestFile(t, path, "=== sshlink started ===\n")
+
+	// Starting sshlink leaves the user's file alone
+	setupLogging(false)
+	if result, found := checkLegacyLog(path); !found || result.Status != "warn" {
+		t.Errorf("Expected a warning for %s, got %+v", path, result)
+	}
+
+	os.Remove(path)
+	if result, found := checkLegacyLog(path); found {
+		t.Errorf("Expected no result once removed, got %+v", result)
+	}
+}
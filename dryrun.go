@@ -0,0 +1,63 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/icanhazstring/sshlink/logging"
+	"github.com/icanhazstring/sshlink/terminals"
+)
+
+// dryRunEnabled reports whether dry_run=on is set in the config
+func dryRunEnabled() bool {
+	enabled, _ := parseSwitch(readConfigValue("dry_run"))
+	return enabled
+}
+
+func runOpen(args []string) error {
+	fs := flag.NewFlagSet("open", flag.ExitOnError)
+	terminal := fs.String("terminal", "terminal", "Terminal to use")
+	dryRun := fs.Bool("dry-run", false, "Print the commands that would run instead of running them")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: sshlink open [options] <sshlink://host>\n\nOptions:\n")
+		fs.PrintDefaults()
+	}
+	positional := parseInterspersed(fs, args)
+	if len(positional) != 1 {
+		fs.Usage()
+		return fmt.Errorf("expected exactly one URL")
+	}
+
+	return openLink(positional[0], resolveTerminalType(*terminal), *dryRun || dryRunEnabled())
+}
+
+// printDryRun shows what executeSSH would start for s. Clicked links have
+// nobody watching stdout, so the first command is also sent as a notification.
+func printDryRun(s session, terminalName, profile string, invocations []terminals.Invocation) error {
+	fmt.Println("🧪 Dry run, nothing was started")
+	fmt.Printf("   Terminal: %s\n", terminalName)
+	if !s.background {
+		fmt.Printf("   Title:    %s: %s\n", s.label, s.name)
+	}
+	if profile != "" {
+		fmt.Printf("   Profile:  %s\n", profile)
+	}
+	if s.preflight != "" {
+		fmt.Printf("   Would check %s is reachable first\n", s.preflight)
+	}
+
+	lines := make([]string, len(invocations))
+	for i, invocation := range invocations {
+		lines[i] = invocation.String()
+		fmt.Printf("$ %s\n", lines[i])
+		logging.Infof("Dry run: %s", lines[i])
+	}
+
+	if len(lines) > 0 {
+		if err := notifier.Notify("sshlink: dry run", strings.Join(lines, "\n")); err != nil {
+			logging.Warnf("Failed to show notification: %v", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/icanhazstring/sshlink/terminals"
+)
+
+func TestDryRun(t *testing.T) {
+	recorder := &recordingNotifier{}
+	originalNotifier := notifier
+	defer func() { notifier = originalNotifier }()
+	notifier = recorder
+
+	mock := &MockTerminal{}
+	originalTestCreateTerminal := terminals.TestCreateTerminal
+	defer func() { terminals.TestCreateTerminal = originalTestCreateTerminal }()
+	terminals.TestCreateTerminal = func(terminalType string) (terminals.Terminal, error) {
+		return mock, nil
+	}
+
+	os.Remove(auditPath())
+	os.Remove(historyPath())
+
+	tests := []struct {
+		url      string
+		expected string
+	}{
+		{"sshlink://deploy@app1:2222", "mock-terminal ssh -p 2222 deploy@app1"},
+		{"sshlink://deploy@app1:2222?cd=/srv", `mock-terminal ssh -t -p 2222 deploy@app1 'cd /srv && exec "${SHELL:-/bin/sh}" -l'`},
+		{"sshlink://bastion?forward=5432:db:5432&background=1", "ssh -N -o ExitOnForwardFailure=yes -o BatchMode=yes -L 5432:db:5432 bastion"},
+	}
+	for i, tt := range tests {
+		if err := runOpen([]string{tt.url, "-dry-run"}); err != nil {
+			t.Fatalf("open -dry-run %s failed: %v", tt.url, err)
+		}
+		if len(recorder.titles) != i+1 || recorder.titles[i] != "sshlink: dry run" {
+			t.Fatalf("Expected a dry run notification for %s, got %v", tt.url, recorder.titles)
+		}
+		if recorder.messages[i] != tt.expected {
+			t.Errorf("%s: expected\n%s\ngot\n%s", tt.url, tt.expected, recorder.messages[i])
+		}
+	}
+
+	if mock.capturedCommand != "" {
+		t.Errorf("Expected nothing to be started, got %q", mock.capturedCommand)
+	}
+
+	records, _ := readAudit()
+	for _, record := range records {
+		if record.Outcome != "dry-run" {
+			t.Errorf("Expected dry-run outcome, got %+v", record)
+		}
+	}
+	if entries, _ := loadHistory(); len(entries) != 0 {
+		t.Errorf("Expected dry runs to stay out of the history, got %+v", entries)
+	}
+	if _, err := os.Stat(tunnelsDir()); err == nil {
+		if entries, _ := os.ReadDir(tunnelsDir()); len(entries) != 0 {
+			t.Errorf("Expected no background tunnel to be recorded")
+		}
+	}
+}
+
+func TestInvocationString(t *testing.T) {
+	invocation := terminals.Invocation{Name: "gnome-terminal", Args: []string{"--tab", "--title=ssh: app1", "--", "/bin/bash", "-c", "ssh app1; exec /bin/bash"}}
+	expected := `gnome-terminal --tab '--title=ssh: app1' -- /bin/bash -c 'ssh app1; exec /bin/bash'`
+	if got := invocation.String(); got != expected {
+		t.Errorf("Expected %s, got %s", expected, got)
+	}
+
+	clipboard := terminals.Invocation{Name: "pbcopy", Stdin: "ssh app1"}
+	if got := clipboard.String(); !strings.HasSuffix(got, "<<< 'ssh app1'") {
+		t.Errorf("Expected stdin in %s", got)
+	}
+}
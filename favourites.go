@@ -0,0 +1,208 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/icanhazstring/sshlink/inventory"
+	"github.com/icanhazstring/sshlink/logging"
+)
+
+// favourite is a bookmarked link with a display name and tags
+type favourite struct {
+	Name  string    `json:"name"`
+	URL   string    `json:"url"`
+	Tags  []string  `json:"tags,omitempty"`
+	Added time.Time `json:"added"`
+}
+
+func favouritesPath() string {
+	prefsFile := configPath()
+	if prefsFile == "" {
+		return ""
+	}
+	return filepath.Join(filepath.Dir(prefsFile), "favourites.json")
+}
+
+func loadFavourites() ([]favourite, error) {
+	content, err := os.ReadFile(favouritesPath())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var favs []favourite
+	if err := json.Unmarshal(content, &favs); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", favouritesPath(), err)
+	}
+	return favs, nil
+}
+
+func saveFavourites(favs []favourite) error {
+	favsFile := favouritesPath()
+	if err := os.MkdirAll(filepath.Dir(favsFile), 0755); err != nil {
+		return fmt.Errorf("failed to create config directory: %v", err)
+	}
+	content, err := json.MarshalIndent(favs, "", "  ")
+	if err != nil {
+		return err
+	}
+	// Links may carry credentials, keep them private like the history
+	if err := writeFileAtomic(favsFile, append(content, '\n'), 0600); err != nil {
+		return fmt.Errorf("failed to write favourites: %v", err)
+	}
+	return nil
+}
+
+// addFavourite stores a link, or updates the name and tags of a link
+// that is already a favourite
+func addFavourite(favs []favourite, link, name string, tags []string) ([]favourite, error) {
+	u, _, err := parseLinkURL(link)
+	if err != nil {
+		return nil, err
+	}
+	if name == "" {
+		name = u.Host + u.Path
+	}
+
+	for _, f := range favs {
+		if f.Name == name && f.URL != link {
+			return nil, fmt.Errorf("favourite %q already exists for %s", name, f.URL)
+		}
+	}
+
+	for i := range favs {
+		if favs[i].URL == link {
+			favs[i].Name = name
+			for _, tag := range tags {
+				if !slices.Contains(favs[i].Tags, tag) {
+					favs[i].Tags = append(favs[i].Tags, tag)
+				}
+			}
+			return favs, nil
+		}
+	}
+	return append(favs, favourite{Name: name, URL: link, Tags: tags, Added: time.Now()}), nil
+}
+
+// favouriteHosts turns favourites into inventory hosts for gen and page
+func favouriteHosts() ([]inventory.Host, error) {
+	favs, err := loadFavourites()
+	if err != nil {
+		return nil, err
+	}
+
+	hosts := make([]inventory.Host, 0, len(favs))
+	for _, f := range favs {
+		// The URL ends up in generated href attributes as is, so a
+		// hand-edited entry must still be one of our links
+		u, _, err := parseLinkURL(f.URL)
+		if err != nil {
+			logging.Warnf("Skipping favourite %q: %v", f.Name, err)
+			continue
+		}
+		hosts = append(hosts, inventory.Host{
+			Name: f.Name,
+			URL:  f.URL,
+			Tags: f.Tags,
+			Host: u.Hostname(),
+			Port: u.Port(),
+			User: u.User.Username(),
+		})
+	}
+	return hosts, nil
+}
+
+func runFav(args []string) error {
+	usage := func() {
+		fmt.Fprintf(os.Stderr, "Usage: sshlink fav add <url> [-name NAME] [-tag TAG]...\n       sshlink fav list [-tag TAG]\n       sshlink fav remove <name|url>\n")
+	}
+	if len(args) == 0 {
+		usage()
+		return fmt.Errorf("expected add, list or remove")
+	}
+
+	favs, err := loadFavourites()
+	if err != nil {
+		return err
+	}
+
+	fs := flag.NewFlagSet("fav "+args[0], flag.ExitOnError)
+	fs.Usage = func() {
+		usage()
+		fmt.Fprintf(fs.Output(), "\nOptions:\n")
+		fs.PrintDefaults()
+	}
+
+	switch args[0] {
+	case "add":
+		name := fs.String("name", "", "Display name (default: the link's host)")
+		var tags stringList
+		fs.Var(&tags, "tag", "Tag for grouping and filtering, may be repeated")
+		positional := parseInterspersed(fs, args[1:])
+		if len(positional) != 1 {
+			fs.Usage()
+			return fmt.Errorf("expected exactly one URL")
+		}
+
+		favs, err = addFavourite(favs, positional[0], *name, tags)
+		if err != nil {
+			return err
+		}
+		if err := saveFavourites(favs); err != nil {
+			return err
+		}
+		fmt.Printf("⭐ Saved favourite: %s\n", positional[0])
+		return nil
+
+	case "list":
+		tag := fs.String("tag", "", "Only list favourites with this tag")
+		parseInterspersed(fs, args[1:])
+
+		listed := 0
+		for _, f := range favs {
+			if *tag != "" && !slices.Contains(f.Tags, *tag) {
+				continue
+			}
+			fmt.Printf("%-24s %-40s %s\n", f.Name, f.URL, strings.Join(f.Tags, ","))
+			listed++
+		}
+		if listed == 0 {
+			fmt.Println("No favourites")
+		}
+		return nil
+
+	case "remove", "rm":
+		positional := parseInterspersed(fs, args[1:])
+		if len(positional) != 1 {
+			fs.Usage()
+			return fmt.Errorf("expected a favourite name or URL")
+		}
+
+		kept := favs[:0]
+		for _, f := range favs {
+			if f.Name != positional[0] && f.URL != positional[0] {
+				kept = append(kept, f)
+			}
+		}
+		if len(kept) == len(favs) {
+			return fmt.Errorf("no favourite %q", positional[0])
+		}
+		if err := saveFavourites(kept); err != nil {
+			return err
+		}
+		fmt.Printf("🗑️  Removed favourite: %s\n", positional[0])
+		return nil
+
+	default:
+		usage()
+		return fmt.Errorf("unknown fav command: %s", args[0])
+	}
+}
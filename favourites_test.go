@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestAddFavourite(t *testing.T) {
+	favs, err := addFavourite(nil, "sshlink://deploy@prod-db-1", "Prod DB", []string{"prod"})
+	if err != nil {
+		t.Fatalf("addFavourite failed: %v", err)
+	}
+	favs, err = addFavourite(favs, "sshlink://deploy@prod-db-1", "Prod DB", []string{"prod", "db"})
+	if err != nil {
+		t.Fatalf("addFavourite failed: %v", err)
+	}
+	if len(favs) != 1 || strings.Join(favs[0].Tags, ",") != "prod,db" {
+		t.Errorf("Expected one favourite tagged prod,db, got %+v", favs)
+	}
+
+	favs, err = addFavourite(favs, "sshlink://app1?cd=/srv", "", nil)
+	if err != nil {
+		t.Fatalf("addFavourite failed: %v", err)
+	}
+	if favs[1].Name != "app1" {
+		t.Errorf("Expected default name app1, got %q", favs[1].Name)
+	}
+
+	if _, err := addFavourite(favs, "sshlink://other", "Prod DB", nil); err == nil {
+		t.Errorf("Expected error for duplicate name")
+	}
+	if _, err := addFavourite(favs, "javascript://alert", "", nil); err == nil {
+		t.Errorf("Expected error for unsupported scheme")
+	}
+}
+
+func TestParseInterspersed(t *testing.T) {
+	fs := flag.NewFlagSet("fav add", flag.ContinueOnError)
+	name := fs.String("name", "", "")
+	var tags stringList
+	fs.Var(&tags, "tag", "")
+
+	positional := parseInterspersed(fs, []string{"sshlink://db1", "--tag", "prod", "-name", "Prod DB", "-tag", "db,eu"})
+	if len(positional) != 1 || positional[0] != "sshlink://db1" {
+		t.Errorf("Expected the URL as only positional argument, got %v", positional)
+	}
+	if *name != "Prod DB" || strings.Join(tags, ",") != "prod,db,eu" {
+		t.Errorf("Unexpected flags: name=%q tags=%v", *name, tags)
+	}
+}
+
+func TestFavouritesAsHostSource(t *testing.T) {
+	defer os.Remove(favouritesPath())
+	if err := runFav([]string{"add", "sshlink://deploy@app1:2222?cd=/srv", "-tag", "prod", "-name", "App"}); err != nil {
+		t.Fatalf("fav add failed: %v", err)
+	}
+	if err := runFav([]string{"add", "sshlink://db1", "-tag", "staging"}); err != nil {
+		t.Fatalf("fav add failed: %v", err)
+	}
+
+	source := hostSource{favourites: true, tag: "prod"}
+	hosts, err := source.load()
+	if err != nil {
+		t.Fatalf("load failed: %v", err)
+	}
+	if len(hosts) != 1 || hosts[0].Host != "app1" || hosts[0].User != "deploy" || hosts[0].Port != "2222" {
+		t.Fatalf("Unexpected hosts: %+v", hosts)
+	}
+
+	var buf bytes.Buffer
+	if err := writeLinks(&buf, hosts, "list"); err != nil {
+		t.Fatal(err)
+	}
+	if strings.TrimSpace(buf.String()) != "sshlink://deploy@app1:2222?cd=/srv" {
+		t.Errorf("Expected the saved link to be kept, got %q", buf.String())
+	}
+
+	if err := runFav([]string{"remove", "App"}); err != nil {
+		t.Fatalf("fav remove failed: %v", err)
+	}
+	if favs, _ := loadFavourites(); len(favs) != 1 {
+		t.Errorf("Expected one favourite left, got %+v", favs)
+	}
+	if info, err := os.Stat(favouritesPath()); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("Expected favourites to be private, got %v, %v", info, err)
+	}
+}
+
+func TestHandEditedFavouriteIsSkipped(t *testing.T) {
+	defer os.Remove(favouritesPath())
+	if err := saveFavourites([]favourite{
+		{Name: "Evil", URL: "javascript:alert(document.cookie)"},
+		{Name: "DB", URL: "sshlink://db1"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	hosts, err := favouriteHosts()
+	if err != nil {
+		t.Fatalf("favouriteHosts failed: %v", err)
+	}
+	if len(hosts) != 1 || hosts[0].Name != "DB" {
+		t.Fatalf("Expected only the valid favourite, got %+v", hosts)
+	}
+
+	var buf bytes.Buffer
+	if err := writeLinks(&buf, hosts, "html"); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(buf.String(), "javascript:") {
+		t.Errorf("Expected no javascript: link, got %s", buf.String())
+	}
+}
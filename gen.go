@@ -0,0 +1,168 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/icanhazstring/sshlink/inventory"
+	"github.com/icanhazstring/sshlink/sshconfig"
+)
+
+// hostSource holds the flags shared by commands that read hosts from an inventory
+type hostSource struct {
+	sshConfig  string
+	ansible    string
+	csv        string
+	resolve    bool
+	tag        string
+	favourites bool
+}
+
+func (s *hostSource) register(fs *flag.FlagSet) {
+	fs.StringVar(&s.sshConfig, "ssh-config", sshconfig.DefaultPath(), "ssh_config file to read Host aliases from")
+	fs.StringVar(&s.ansible, "ansible", "", "Ansible inventory file (INI or YAML) to read hosts from")
+	fs.BoolVar(&s.favourites, "favourites", false, "Read hosts from favourites saved with \"sshlink fav add\"")
+	fs.StringVar(&s.csv, "csv", "", "CSV file with host,name,user,port,tags columns to read hosts from")
+	fs.BoolVar(&s.resolve, "resolve", false, "Use resolved HostName/User/Port instead of ssh_config aliases")
+	fs.StringVar(&s.tag, "tag", "", "Only include hosts with this tag")
+}
+
+// load reads hosts from the selected source, defaulting to ~/.ssh/config
+func (s *hostSource) load() ([]inventory.Host, error) {
+	var hosts []inventory.Host
+	switch {
+	case s.favourites:
+		loaded, err := favouriteHosts()
+		if err != nil {
+			return nil, err
+		}
+		hosts = loaded
+	case s.ansible != "":
+		loaded, err := inventory.Load(s.ansible)
+		if err != nil {
+			return nil, err
+		}
+		hosts = loaded
+	case s.csv != "":
+		loaded, err := inventory.Load(s.csv)
+		if err != nil {
+			return nil, err
+		}
+		hosts = loaded
+	default:
+		cfg, err := sshconfig.Load(s.sshConfig)
+		if err != nil {
+			return nil, err
+		}
+		hosts = inventory.FromSSHConfig(cfg, s.resolve)
+	}
+
+	if s.tag == "" {
+		return hosts, nil
+	}
+	var filtered []inventory.Host
+	for _, h := range hosts {
+		if h.HasTag(s.tag) {
+			filtered = append(filtered, h)
+		}
+	}
+	return filtered, nil
+}
+
+func runGen(args []string) error {
+	var source hostSource
+	fs := flag.NewFlagSet("gen", flag.ExitOnError)
+	source.register(fs)
+	format := fs.String("format", "list", "Output format (list, markdown, html, json)")
+	output := fs.String("o", "", "Write to file instead of stdout")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: sshlink gen [options]\n\nOptions:\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	hosts, err := source.load()
+	if err != nil {
+		return err
+	}
+
+	var w io.Writer = os.Stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			return fmt.Errorf("failed to create output file: %v", err)
+		}
+		defer f.Close()
+		w = f
+	}
+
+	return writeLinks(w, hosts, *format)
+}
+
+func writeLinks(w io.Writer, hosts []inventory.Host, format string) error {
+	switch format {
+	case "list":
+		for _, h := range hosts {
+			fmt.Fprintln(w, h.Link())
+		}
+		return nil
+
+	case "markdown", "md":
+		fmt.Fprintln(w, "| Name | Link | Tags |")
+		fmt.Fprintln(w, "|------|------|------|")
+		for _, h := range hosts {
+			fmt.Fprintf(w, "| %s | [%s](%s) | %s |\n",
+				markdownEscape(h.Name), markdownEscape(h.Name), h.Link(),
+				markdownEscape(strings.Join(h.Tags, ", ")))
+		}
+		return nil
+
+	case "html":
+		return linkTableTemplate.Execute(w, hosts)
+
+	case "json":
+		type entry struct {
+			inventory.Host
+			Link string `json:"link"`
+		}
+		entries := make([]entry, 0, len(hosts))
+		for _, h := range hosts {
+			entries = append(entries, entry{Host: h, Link: h.Link()})
+		}
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(entries)
+
+	default:
+		return fmt.Errorf("unsupported format: %s", format)
+	}
+}
+
+func markdownEscape(s string) string {
+	return strings.NewReplacer("|", `\|`, "[", `\[`, "]", `\]`).Replace(s)
+}
+
+// linkFuncs marks generated links as safe; html/template would otherwise
+// replace the non-http sshlink:// scheme with "#ZgotmplZ"
+var linkFuncs = template.FuncMap{
+	"link": func(h inventory.Host) template.URL {
+		return template.URL(h.Link())
+	},
+}
+
+var linkTableTemplate = template.Must(template.New("links").Funcs(linkFuncs).Parse(`<table>
+  <thead>
+    <tr><th>Name</th><th>Target</th><th>Tags</th></tr>
+  </thead>
+  <tbody>
+{{- range .}}
+    <tr><td>{{.Name}}</td><td><a href="{{link .}}">{{.Link}}</a></td><td>{{range $i, $t := .Tags}}{{if $i}}, {{end}}{{$t}}{{end}}</td></tr>
+{{- end}}
+  </tbody>
+</table>
+`))
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/icanhazstring/sshlink/logging"
+)
+
+// maxHistoryEntries bounds the history; the least frecent entries go first
+const maxHistoryEntries = 200
+
+// historyEntry is one host opened through sshlink
+type historyEntry struct {
+	Link  string    `json:"link"`
+	Host  string    `json:"host"`
+	User  string    `json:"user,omitempty"`
+	Port  string    `json:"port,omitempty"`
+	First time.Time `json:"first"`
+	Last  time.Time `json:"last"`
+	Count int       `json:"count"`
+}
+
+func historyPath() string {
+	dir := stateDir()
+	if dir == "" {
+		return ""
+	}
+	return filepath.Join(dir, "history.json")
+}
+
+// historyLink is the link recorded for reopening: scheme, user, host, port
+// and path, without query parameters (commands, forwards) or passwords
+func historyLink(u *url.URL) string {
+	link := url.URL{Scheme: u.Scheme, Host: u.Host, Path: u.Path}
+	if u.User != nil {
+		link.User = url.User(u.User.Username())
+	}
+	return link.String()
+}
+
+// frecency ranks entries by how often and how recently they were opened
+func frecency(e historyEntry, now time.Time) float64 {
+	age := now.Sub(e.Last)
+	weight := 10.0
+	switch {
+	case age < 4*24*time.Hour:
+		weight = 100
+	case age < 14*24*time.Hour:
+		weight = 70
+	case age < 31*24*time.Hour:
+		weight = 50
+	case age < 90*24*time.Hour:
+		weight = 30
+	}
+	return float64(e.Count) * weight
+}
+
+func sortByFrecency(entries []historyEntry, now time.Time) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		a, b := frecency(entries[i], now), frecency(entries[j], now)
+		if a != b {
+			return a > b
+		}
+		return entries[i].Last.After(entries[j].Last)
+	})
+}
+
+func loadHistory() ([]historyEntry, error) {
+	content, err := os.ReadFile(historyPath())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var entries []historyEntry
+	if err := json.Unmarshal(content, &entries); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", historyPath(), err)
+	}
+	return entries, nil
+}
+
+func saveHistory(entries []historyEntry) error {
+	historyFile := historyPath()
+	if err := os.MkdirAll(filepath.Dir(historyFile), 0700); err != nil {
+		return err
+	}
+	content, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// Write to a temp file first so a crash never leaves half a history
+	tmp := historyFile + ".tmp"
+	if err := os.WriteFile(tmp, content, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, historyFile)
+}
+
+// recordHistory counts a successfully opened session. Failing to record
+// is logged but never fails the launch.
+func recordHistory(s session) {
+	if s.link == "" {
+		return
+	}
+	if err := addHistory(s, time.Now()); err != nil {
+		logging.Warnf("Failed to record history: %v", err)
+	}
+}
+
+func addHistory(s session, now time.Time) error {
+	entries, err := loadHistory()
+	if err != nil {
+		return err
+	}
+
+	found := false
+	for i := range entries {
+		if entries[i].Link == s.link {
+			entries[i].Last = now
+			entries[i].Count++
+			found = true
+			break
+		}
+	}
+	if !found {
+		entries = append(entries, historyEntry{
+			Link:  s.link,
+			Host:  s.target.Host,
+			User:  s.target.User,
+			Port:  s.target.Port,
+			First: now,
+			Last:  now,
+			Count: 1,
+		})
+	}
+
+	sortByFrecency(entries, now)
+	if len(entries) > maxHistoryEntries {
+		entries = entries[:maxHistoryEntries]
+	}
+	return saveHistory(entries)
+}
+
+// recentHistory returns the history in the order shown by "sshlink recent"
+func recentHistory() ([]historyEntry, error) {
+	entries, err := loadHistory()
+	if err != nil {
+		return nil, err
+	}
+	sortByFrecency(entries, time.Now())
+	return entries, nil
+}
+
+func runRecent(args []string) error {
+	fs := flag.NewFlagSet("recent", flag.ExitOnError)
+	count := fs.Int("n", 20, "Number of entries to show")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: sshlink recent [-n 20]\n\nOptions:\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	entries, err := recentHistory()
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		fmt.Println("No history yet")
+		return nil
+	}
+	if *count >= 0 && len(entries) > *count {
+		entries = entries[:*count]
+	}
+
+	for i, e := range entries {
+		fmt.Printf("%3d  %-40s %4dx  (last %s)\n", i+1, e.Link, e.Count, e.Last.Local().Format(time.DateTime))
+	}
+	return nil
+}
+
+func runReopen(args []string) error {
+	fs := flag.NewFlagSet("reopen", flag.ExitOnError)
+	terminal := fs.String("terminal", "terminal", "Terminal to use")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: sshlink reopen [options] <n>\n\n<n> is the number shown by \"sshlink recent\"\n\nOptions:\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return fmt.Errorf("expected exactly one entry number")
+	}
+
+	n, err := strconv.Atoi(fs.Arg(0))
+	if err != nil || n < 1 {
+		return fmt.Errorf("invalid entry number: %q", fs.Arg(0))
+	}
+
+	entries, err := recentHistory()
+	if err != nil {
+		return err
+	}
+	if n > len(entries) {
+		return fmt.Errorf("no history entry %d (%d recorded)", n, len(entries))
+	}
+
+	// Go through the normal pipeline so validation and policy still apply
+	return handleURL(entries[n-1].Link, resolveTerminalType(*terminal))
+}
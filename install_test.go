@@ -0,0 +1,43 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/icanhazstring/sshlink/terminals"
+)
+
+func TestRegisterProtocolHandler(t *testing.T) {
+	runner := &terminals.RecordingRunner{
+		Respond: func(i terminals.Invocation) ([]byte, error) {
+			if i.Name == "xdg-mime" && i.Args[0] == "query" {
+				return []byte("sshlink.desktop\n"), nil
+			}
+			return nil, nil
+		},
+	}
+	originalRunner := terminals.DefaultRunner
+	defer func() { terminals.DefaultRunner = originalRunner }()
+	terminals.DefaultRunner = runner
+
+	if err := registerProtocolHandler(); err != nil {
+		t.Fatalf("registerProtocolHandler failed: %v", err)
+	}
+
+	commands := runner.Commands()
+	if len(commands) == 0 || !strings.HasPrefix(commands[0], "update-desktop-database ") {
+		t.Fatalf("Expected the desktop database to be updated first, got %v", commands)
+	}
+	for _, scheme := range schemeNames() {
+		expected := "xdg-mime default sshlink.desktop x-scheme-handler/" + scheme
+		found := false
+		for _, command := range commands {
+			if command == expected {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("Expected %q in %v", expected, commands)
+		}
+	}
+}
@@ -0,0 +1,282 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// installState remembers what install changed, so re-installing can skip
+// unchanged files and uninstall can put back what was there before
+type installState struct {
+	Version string `json:"version"`
+	// Handlers maps a scheme to the default handler before install,
+	// "" when there was none
+	Handlers map[string]string `json:"handlers"`
+	// Backups maps a file to its content before install, nil when the
+	// file didn't exist
+	Backups map[string]*string `json:"backups"`
+	// Modes maps a backed up file to its permissions before install
+	Modes map[string]os.FileMode `json:"modes,omitempty"`
+	// Checksums maps a file to the sha256 of what install wrote
+	Checksums map[string]string `json:"checksums"`
+}
+
+func installStatePath() string {
+	dir := stateDir()
+	if dir == "" {
+		return ""
+	}
+	return filepath.Join(dir, "install.json")
+}
+
+// loadInstallState returns the recorded state, or an empty one before
+// the first install
+func loadInstallState() (*installState, error) {
+	st := &installState{
+		Handlers:  map[string]string{},
+		Backups:   map[string]*string{},
+		Modes:     map[string]os.FileMode{},
+		Checksums: map[string]string{},
+	}
+	content, err := os.ReadFile(installStatePath())
+	if os.IsNotExist(err) {
+		return st, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(content, st); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", installStatePath(), err)
+	}
+	if st.Modes == nil {
+		st.Modes = map[string]os.FileMode{} // recorded before modes were kept
+	}
+	return st, nil
+}
+
+func (st *installState) save() error {
+	statePath := installStatePath()
+	if err := os.MkdirAll(filepath.Dir(statePath), 0700); err != nil {
+		return fmt.Errorf("failed to create state directory: %v", err)
+	}
+	content, err := json.MarshalIndent(st, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(statePath, content, 0600)
+}
+
+func (st *installState) remove() error {
+	if err := os.Remove(installStatePath()); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// writeFile writes content to path unless it is already there, backing up
+// the previous content and permissions the first time sshlink touches the
+// file. An existing file keeps its permissions, mode applies to new ones.
+func (st *installState) writeFile(path string, content []byte, mode os.FileMode) (bool, error) {
+	current, err := os.ReadFile(path)
+	exists := err == nil
+	if err != nil && !os.IsNotExist(err) {
+		return false, err
+	}
+	if exists {
+		info, err := os.Stat(path)
+		if err != nil {
+			return false, err
+		}
+		mode = info.Mode().Perm()
+	}
+
+	if _, recorded := st.Backups[path]; !recorded {
+		if exists {
+			backup := string(current)
+			st.Backups[path] = &backup
+			st.Modes[path] = mode
+		} else {
+			st.Backups[path] = nil
+		}
+	}
+
+	if exists && bytes.Equal(current, content) {
+		return false, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return false, err
+	}
+	if err := writeFileAtomic(path, content, mode); err != nil {
+		return false, err
+	}
+	st.Checksums[path] = checksum(content)
+	return true, nil
+}
+
+// restoreFiles puts back the content files had before install. Files
+// changed since install are kept, so user edits aren't lost.
+func (st *installState) restoreFiles() error {
+	for path, backup := range st.Backups {
+		current, err := os.ReadFile(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if sum, ok := st.Checksums[path]; ok && checksum(current) != sum {
+			fmt.Printf("ℹ️  Keeping %s, it was changed after install\n", path)
+			continue
+		}
+
+		if backup == nil {
+			if err := os.Remove(path); err != nil {
+				return fmt.Errorf("failed to remove %s: %v", path, err)
+			}
+			fmt.Printf("🗑️  Removed: %s\n", path)
+			continue
+		}
+		mode, ok := st.Modes[path]
+		if !ok {
+			mode = 0644
+		}
+		if err := writeFileAtomic(path, []byte(*backup), mode); err != nil {
+			return fmt.Errorf("failed to restore %s: %v", path, err)
+		}
+		fmt.Printf("↩️  Restored: %s\n", path)
+	}
+	return nil
+}
+
+// writeFileAtomic writes to a temp file next to path and renames it into
+// place, so readers never see a half-written file. A symlinked path, e.g. a
+// mimeapps.list kept by a dotfile manager, is written through to its target.
+func writeFileAtomic(path string, content []byte, mode os.FileMode) error {
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(mode); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func checksum(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// setConfigValue sets key in key=value content, replacing the first
+// existing entry and keeping every other line as it is
+func setConfigValue(content, key, value string) string {
+	lines := strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+	if content == "" {
+		lines = nil
+	}
+	for i, line := range lines {
+		if strings.HasPrefix(strings.TrimSpace(line), key+"=") {
+			lines[i] = key + "=" + value
+			return strings.Join(lines, "\n") + "\n"
+		}
+	}
+	return strings.Join(append(lines, key+"="+value), "\n") + "\n"
+}
+
+// mimeAppsPath is the user's mimeapps.list, where xdg-mime stores defaults
+func mimeAppsPath() string {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "mimeapps.list")
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(homeDir, ".config", "mimeapps.list")
+}
+
+// setMimeDefault sets (or with an empty handler, removes) the default for
+// mimeType in the [Default Applications] section of mimeapps.list content.
+// sshlink.desktop is also dropped from [Added Associations].
+func setMimeDefault(content, mimeType, handler string) string {
+	var out []string
+	section := ""
+	written := false
+	flush := func() {
+		if section == "[Default Applications]" && !written && handler != "" {
+			out = append(out, mimeType+"="+handler)
+			written = true
+		}
+	}
+
+	lines := strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+	if content == "" {
+		lines = nil
+	}
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "[") {
+			flush()
+			section = trimmed
+			out = append(out, line)
+			continue
+		}
+
+		key, value, ok := strings.Cut(trimmed, "=")
+		if !ok || strings.TrimSpace(key) != mimeType {
+			out = append(out, line)
+			continue
+		}
+
+		switch section {
+		case "[Default Applications]":
+			if handler != "" && !written {
+				out = append(out, mimeType+"="+handler)
+				written = true
+			}
+		case "[Added Associations]":
+			var kept []string
+			for _, desktop := range strings.Split(value, ";") {
+				if desktop != "" && desktop != "sshlink.desktop" {
+					kept = append(kept, desktop)
+				}
+			}
+			if len(kept) > 0 {
+				out = append(out, mimeType+"="+strings.Join(kept, ";")+";")
+			}
+		default:
+			out = append(out, line)
+		}
+	}
+	flush()
+
+	if !written && handler != "" {
+		out = append(out, "[Default Applications]", mimeType+"="+handler)
+	}
+	if len(out) == 0 {
+		return ""
+	}
+	return strings.Join(out, "\n") + "\n"
+}
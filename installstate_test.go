@@ -0,0 +1,196 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/icanhazstring/sshlink/terminals"
+)
+
+func TestSetMimeDefault(t *testing.T) {
+	content := `[Default Applications]
+text/html=firefox.desktop
+x-scheme-handler/sshlink=sshlink.desktop
+
+[Added Associations]
+x-scheme-handler/sshlink=sshlink.desktop;other.desktop;
+`
+
+	removed := setMimeDefault(content, "x-scheme-handler/sshlink", "")
+	expected := `[Default Applications]
+text/html=firefox.desktop
+
+[Added Associations]
+x-scheme-handler/sshlink=other.desktop;
+`
+	if removed != expected {
+		t.Errorf("Unexpected content after removing the default:\n%s", removed)
+	}
+
+	restored := setMimeDefault(content, "x-scheme-handler/sshlink", "putty.desktop")
+	if !strings.Contains(restored, "x-scheme-handler/sshlink=putty.desktop\n") || strings.Contains(restored, "=sshlink.desktop") {
+		t.Errorf("Unexpected content after restoring the default:\n%s", restored)
+	}
+
+	added := setMimeDefault("", "x-scheme-handler/sshlink", "putty.desktop")
+	if added != "[Default Applications]\nx-scheme-handler/sshlink=putty.desktop\n" {
+		t.Errorf("Unexpected content for a new file:\n%s", added)
+	}
+}
+
+func TestSetConfigValue(t *testing.T) {
+	content := "profile=prod-*:Production\nterminal=iterm\n"
+	got := setConfigValue(setConfigValue(content, "terminal", "gnome-terminal"), "shell", "/bin/zsh")
+	expected := "profile=prod-*:Production\nterminal=gnome-terminal\nshell=/bin/zsh\n"
+	if got != expected {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+	if got := setConfigValue("", "terminal", "x"); got != "terminal=x\n" {
+		t.Errorf("Unexpected content for an empty config: %q", got)
+	}
+}
+
+// fakeXDGMime keeps scheme defaults in memory like xdg-mime would
+func fakeXDGMime(defaults map[string]string) *terminals.RecordingRunner {
+	return &terminals.RecordingRunner{
+		Respond: func(i terminals.Invocation) ([]byte, error) {
+			if i.Name != "xdg-mime" {
+				return nil, nil
+			}
+			switch i.Args[0] {
+			case "default":
+				defaults[i.Args[2]] = i.Args[1]
+			case "query":
+				return []byte(defaults[i.Args[2]] + "\n"), nil
+			}
+			return nil, nil
+		},
+	}
+}
+
+func TestInstallIdempotentAndRestore(t *testing.T) {
+	home, _ := os.UserHomeDir()
+	configFile := filepath.Join(home, ".config", "sshlink", "config")
+	desktopFile := filepath.Join(home, ".local", "share", "applications", "sshlink.desktop")
+	mimeApps := filepath.Join(home, ".config", "mimeapps.list")
+	defer os.RemoveAll(filepath.Join(home, ".config"))
+	defer os.RemoveAll(filepath.Join(home, ".local"))
+	defer os.Remove(installStatePath())
+
+	os.MkdirAll(filepath.Dir(configFile), 0755)
+	originalConfig := "profile=prod-*:Production\n"
+	os.WriteFile(configFile, []byte(originalConfig), 0600)
+
+	defaults := map[string]string{"x-scheme-handler/sftplink": "filezilla.desktop"}
+	runner := fakeXDGMime(defaults)
+	originalRunner := terminals.DefaultRunner
+	defer func() { terminals.DefaultRunner = originalRunner }()
+	terminals.DefaultRunner = runner
+
+	if err := installHandlerLinux("gnome-terminal"); err != nil {
+		t.Fatalf("install failed: %v", err)
+	}
+	config, _ := os.ReadFile(configFile)
+	if !strings.HasPrefix(string(config), originalConfig) || !strings.Contains(string(config), "terminal=gnome-terminal\n") {
+		t.Errorf("Expected install to keep the existing config, got:\n%s", config)
+	}
+	if info, _ := os.Stat(configFile); info.Mode().Perm() != 0600 {
+		t.Errorf("Expected install to keep the config private, got %v", info.Mode())
+	}
+	if _, err := os.Stat(desktopFile); err != nil {
+		t.Errorf("Expected desktop file: %v", err)
+	}
+	for _, scheme := range schemeNames() {
+		if defaults["x-scheme-handler/"+scheme] != "sshlink.desktop" {
+			t.Errorf("Expected %s to be registered, got %v", scheme, defaults)
+		}
+	}
+
+	// Re-running install changes nothing
+	runner.Invocations = nil
+	if err := installHandlerLinux("gnome-terminal"); err != nil {
+		t.Fatalf("second install failed: %v", err)
+	}
+	for _, command := range runner.Commands() {
+		if !strings.HasPrefix(command, "xdg-mime query ") {
+			t.Errorf("Expected only queries on re-install, got %s", command)
+		}
+	}
+
+	// xdg-mime wrote the sshlink default to mimeapps.list
+	os.WriteFile(mimeApps, []byte("[Default Applications]\nx-scheme-handler/sshlink=sshlink.desktop\n"), 0644)
+
+	if err := uninstallHandlerLinux(); err != nil {
+		t.Fatalf("uninstall failed: %v", err)
+	}
+	if _, err := os.Stat(desktopFile); !os.IsNotExist(err) {
+		t.Errorf("Expected desktop file to be removed")
+	}
+	if config, _ := os.ReadFile(configFile); string(config) != originalConfig {
+		t.Errorf("Expected the original config back, got:\n%s", config)
+	}
+	if info, _ := os.Stat(configFile); info.Mode().Perm() != 0600 {
+		t.Errorf("Expected the original config permissions back, got %v", info.Mode())
+	}
+	if defaults["x-scheme-handler/sftplink"] != "filezilla.desktop" {
+		t.Errorf("Expected sftplink to be handed back to filezilla, got %v", defaults)
+	}
+	if content, _ := os.ReadFile(mimeApps); strings.Contains(string(content), "sshlink.desktop") {
+		t.Errorf("Expected sshlink to be removed from mimeapps.list, got:\n%s", content)
+	}
+	if _, err := os.Stat(installStatePath()); !os.IsNotExist(err) {
+		t.Errorf("Expected install state to be removed")
+	}
+}
+
+func TestUninstallWithoutInstallState(t *testing.T) {
+	home, _ := os.UserHomeDir()
+	desktopFile := filepath.Join(home, ".local", "share", "applications", "sshlink.desktop")
+	favourites := filepath.Join(filepath.Dir(configPath()), "favourites.json")
+	defer os.RemoveAll(filepath.Join(home, ".config"))
+	defer os.RemoveAll(filepath.Join(home, ".local"))
+	os.Remove(installStatePath())
+
+	writeTestFile(t, desktopFile, "[Desktop Entry]\nExec=/usr/bin/sshlink %u\n")
+	writeTestFile(t, configPath(), "terminal=gnome-terminal\n")
+	writeTestFile(t, favourites, "[]\n")
+
+	originalRunner := terminals.DefaultRunner
+	defer func() { terminals.DefaultRunner = originalRunner }()
+	terminals.DefaultRunner = fakeXDGMime(map[string]string{})
+
+	if err := uninstallHandlerLinux(); err != nil {
+		t.Fatalf("uninstall failed: %v", err)
+	}
+	for _, removed := range []string{desktopFile, configPath()} {
+		if _, err := os.Stat(removed); !os.IsNotExist(err) {
+			t.Errorf("Expected %s to be removed", removed)
+		}
+	}
+	if _, err := os.Stat(favourites); err != nil {
+		t.Errorf("Expected favourites to be kept: %v", err)
+	}
+}
+
+func TestWriteFileAtomicFollowsSymlink(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "dotfiles", "mimeapps.list")
+	link := filepath.Join(dir, "mimeapps.list")
+	os.MkdirAll(filepath.Dir(target), 0755)
+	os.WriteFile(target, []byte("[Default Applications]\n"), 0644)
+	if err := os.Symlink(target, link); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+
+	if err := writeFileAtomic(link, []byte("updated\n"), 0644); err != nil {
+		t.Fatalf("writeFileAtomic failed: %v", err)
+	}
+	if info, err := os.Lstat(link); err != nil || info.Mode()&os.ModeSymlink == 0 {
+		t.Errorf("Expected %s to stay a symlink, got %v", link, info)
+	}
+	if content, _ := os.ReadFile(target); string(content) != "updated\n" {
+		t.Errorf("Expected the link target to be updated, got %q", content)
+	}
+}
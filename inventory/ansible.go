@@ -0,0 +1,178 @@
+package inventory
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ParseAnsibleINI reads an Ansible INI inventory. Group names become tags,
+// including groups that contain the host's group via [group:children].
+func ParseAnsibleINI(r io.Reader) ([]Host, error) {
+	c := newCollector()
+	parents := map[string][]string{} // child group -> parent groups
+	type entry struct {
+		name  string
+		vars  map[string]string
+		group string
+	}
+	var entries []entry
+
+	group, section := "ungrouped", "hosts"
+	scanner := bufio.NewScanner(r)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") {
+			if !strings.HasSuffix(line, "]") {
+				return nil, fmt.Errorf("line %d: malformed section %q", lineNo, line)
+			}
+			name := line[1 : len(line)-1]
+			group, section = name, "hosts"
+			if g, kind, ok := strings.Cut(name, ":"); ok {
+				group, section = g, kind
+			}
+			continue
+		}
+
+		fields := strings.Fields(line)
+		switch section {
+		case "hosts":
+			vars := map[string]string{}
+			for _, field := range fields[1:] {
+				if key, value, ok := strings.Cut(field, "="); ok {
+					vars[key] = strings.Trim(value, `"'`)
+				}
+			}
+			entries = append(entries, entry{name: fields[0], vars: vars, group: group})
+		case "children":
+			parents[fields[0]] = append(parents[fields[0]], group)
+		}
+		// [group:vars] only sets Ansible variables, nothing to link
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, e := range entries {
+		c.add(e.name, e.vars, groupAncestry(e.group, parents)...)
+	}
+	return c.list(), nil
+}
+
+// groupAncestry returns group followed by every group that includes it
+func groupAncestry(group string, parents map[string][]string) []string {
+	seen := map[string]bool{}
+	var groups []string
+	queue := []string{group}
+	for len(queue) > 0 {
+		g := queue[0]
+		queue = queue[1:]
+		if seen[g] {
+			continue
+		}
+		seen[g] = true
+		groups = append(groups, g)
+		queue = append(queue, parents[g]...)
+	}
+	return groups
+}
+
+// yamlNode is one "key:" or "key: value" line of a YAML mapping
+type yamlNode struct {
+	key      string
+	value    string
+	children []*yamlNode
+}
+
+func (n *yamlNode) child(key string) *yamlNode {
+	for _, c := range n.children {
+		if c.key == key {
+			return c
+		}
+	}
+	return nil
+}
+
+// ParseAnsibleYAML reads an Ansible YAML inventory (all/hosts/children/vars).
+// Only the block-mapping subset of YAML used by inventories is supported.
+func ParseAnsibleYAML(r io.Reader) ([]Host, error) {
+	root, err := parseYAMLMapping(r)
+	if err != nil {
+		return nil, err
+	}
+
+	c := newCollector()
+	var walk func(group *yamlNode, tags []string)
+	walk = func(group *yamlNode, tags []string) {
+		tags = append(tags, group.key)
+		if hosts := group.child("hosts"); hosts != nil {
+			for _, h := range hosts.children {
+				vars := map[string]string{}
+				for _, v := range h.children {
+					vars[v.key] = v.value
+				}
+				c.add(h.key, vars, tags...)
+			}
+		}
+		if children := group.child("children"); children != nil {
+			for _, child := range children.children {
+				walk(child, tags)
+			}
+		}
+	}
+	for _, group := range root.children {
+		walk(group, nil)
+	}
+	return c.list(), nil
+}
+
+func parseYAMLMapping(r io.Reader) (*yamlNode, error) {
+	root := &yamlNode{}
+	type level struct {
+		indent int
+		node   *yamlNode
+	}
+	stack := []level{{indent: -1, node: root}}
+
+	scanner := bufio.NewScanner(r)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		raw := scanner.Text()
+		trimmed := strings.TrimSpace(raw)
+		if trimmed == "" || trimmed == "---" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		if strings.HasPrefix(trimmed, "- ") {
+			return nil, fmt.Errorf("line %d: YAML sequences are not supported in inventories", lineNo)
+		}
+
+		key, value, ok := strings.Cut(trimmed, ":")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected \"key:\" or \"key: value\"", lineNo)
+		}
+		if i := strings.Index(value, " #"); i >= 0 {
+			value = value[:i]
+		}
+		node := &yamlNode{
+			key:   strings.Trim(strings.TrimSpace(key), `"'`),
+			value: strings.Trim(strings.TrimSpace(value), `"'`),
+		}
+
+		indent := len(raw) - len(strings.TrimLeft(raw, " "))
+		for indent <= stack[len(stack)-1].indent {
+			stack = stack[:len(stack)-1]
+		}
+		parent := stack[len(stack)-1].node
+		parent.children = append(parent.children, node)
+		stack = append(stack, level{indent: indent, node: node})
+	}
+	return root, scanner.Err()
+}
@@ -0,0 +1,65 @@
+package inventory
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ParseCSV reads hosts from a CSV file with a header row. The host column
+// is required; name, user, port and tags (separated by ';' or spaces) are optional.
+func ParseCSV(r io.Reader) ([]Host, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV header: %v", err)
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	if _, ok := columns["host"]; !ok {
+		return nil, fmt.Errorf("CSV header must contain a host column")
+	}
+
+	field := func(record []string, name string) string {
+		i, ok := columns[name]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	var hosts []Host
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		h := Host{
+			Name: field(record, "name"),
+			Host: field(record, "host"),
+			User: field(record, "user"),
+			Port: field(record, "port"),
+		}
+		if h.Host == "" {
+			continue
+		}
+		if h.Name == "" {
+			h.Name = h.Host
+		}
+		h.Tags = strings.FieldsFunc(field(record, "tags"), func(r rune) bool {
+			return r == ';' || r == ' '
+		})
+		hosts = append(hosts, h)
+	}
+	return hosts, nil
+}
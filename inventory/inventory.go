@@ -0,0 +1,138 @@
+package inventory
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/icanhazstring/sshlink/sshconfig"
+)
+
+// Host is a single connectable entry read from an inventory source
+type Host struct {
+	Name string   `json:"name"`
+	Host string   `json:"host"`
+	User string   `json:"user,omitempty"`
+	Port string   `json:"port,omitempty"`
+	Tags []string `json:"tags,omitempty"`
+	// URL is a complete link to use instead of one built from the fields
+	URL string `json:"-"`
+}
+
+// Link renders the host as an sshlink:// URL
+func (h Host) Link() string {
+	if h.URL != "" {
+		return h.URL
+	}
+	u := url.URL{Scheme: "sshlink", Host: h.Host}
+	if h.Port != "" {
+		u.Host = net.JoinHostPort(h.Host, h.Port)
+	} else if strings.Contains(h.Host, ":") {
+		u.Host = "[" + h.Host + "]"
+	}
+	if h.User != "" {
+		u.User = url.User(h.User)
+	}
+	return u.String()
+}
+
+// HasTag reports whether the host carries tag
+func (h Host) HasTag(tag string) bool {
+	for _, t := range h.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// FromSSHConfig lists the literal Host aliases of an ssh_config. With
+// resolve, links carry the resolved HostName/User/Port so they work for
+// people who don't share the config; otherwise they use the alias.
+func FromSSHConfig(cfg *sshconfig.Config, resolve bool) []Host {
+	var hosts []Host
+	for _, alias := range cfg.Hosts() {
+		h := Host{Name: alias, Host: alias}
+		if resolve {
+			settings := cfg.Resolve(alias, "")
+			if hostname := settings["hostname"]; hostname != "" {
+				h.Host = hostname
+			}
+			h.User = settings["user"]
+			if port := settings["port"]; port != "22" {
+				h.Port = port
+			}
+		}
+		hosts = append(hosts, h)
+	}
+	return hosts
+}
+
+// Load reads an Ansible inventory (INI or YAML) or a CSV file,
+// picking the format from the file extension
+func Load(path string) ([]Host, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var hosts []Host
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		hosts, err = ParseCSV(f)
+	case ".yml", ".yaml":
+		hosts, err = ParseAnsibleYAML(f)
+	default:
+		hosts, err = ParseAnsibleINI(f)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return hosts, nil
+}
+
+// collector merges hosts seen in several groups, keeping first-seen order
+type collector struct {
+	hosts []*Host
+	index map[string]*Host
+}
+
+func newCollector() *collector {
+	return &collector{index: map[string]*Host{}}
+}
+
+func (c *collector) add(name string, vars map[string]string, tags ...string) *Host {
+	h, ok := c.index[name]
+	if !ok {
+		h = &Host{Name: name, Host: name}
+		c.index[name] = h
+		c.hosts = append(c.hosts, h)
+	}
+	if v := vars["ansible_host"]; v != "" {
+		h.Host = v
+	}
+	if v := vars["ansible_user"]; v != "" {
+		h.User = v
+	}
+	if v := vars["ansible_port"]; v != "" {
+		h.Port = v
+	}
+	for _, tag := range tags {
+		if tag != "" && tag != "all" && tag != "ungrouped" && !h.HasTag(tag) {
+			h.Tags = append(h.Tags, tag)
+		}
+	}
+	return h
+}
+
+func (c *collector) list() []Host {
+	hosts := make([]Host, 0, len(c.hosts))
+	for _, h := range c.hosts {
+		hosts = append(hosts, *h)
+	}
+	return hosts
+}
@@ -0,0 +1,92 @@
+package inventory
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParsers(t *testing.T) {
+	tests := []struct {
+		name  string
+		parse func() ([]Host, error)
+		want  []string // "link tags"
+	}{
+		{
+			name: "Ansible INI",
+			parse: func() ([]Host, error) {
+				return ParseAnsibleINI(strings.NewReader(`
+ungrouped.example.com
+[web]
+web1.example.com ansible_user=deploy ansible_port=2222
+web2 ansible_host=10.0.0.2
+
+[db]
+web2
+
+[prod:children]
+web
+
+[web:vars]
+ansible_python_interpreter=/usr/bin/python3
+`))
+			},
+			want: []string{
+				"sshlink://ungrouped.example.com ",
+				"sshlink://deploy@web1.example.com:2222 web,prod",
+				"sshlink://10.0.0.2 web,prod,db",
+			},
+		},
+		{
+			name: "Ansible YAML",
+			parse: func() ([]Host, error) {
+				return ParseAnsibleYAML(strings.NewReader(`---
+all:
+  hosts:
+    mail.example.com:
+  children:
+    webservers:
+      hosts:
+        foo.example.com:
+          ansible_host: 192.0.2.10 # primary
+          ansible_user: "deploy"
+      children:
+        canary:
+          hosts:
+            bar.example.com:
+              ansible_port: 2200
+`))
+			},
+			want: []string{
+				"sshlink://mail.example.com ",
+				"sshlink://deploy@192.0.2.10 webservers",
+				"sshlink://bar.example.com:2200 webservers,canary",
+			},
+		},
+		{
+			name: "CSV",
+			parse: func() ([]Host, error) {
+				return ParseCSV(strings.NewReader("name,host,user,port,tags\nProd DB,db.example.com,admin,,prod;db\n,2001:db8::1,,22,\n"))
+			},
+			want: []string{
+				"sshlink://admin@db.example.com prod,db",
+				"sshlink://[2001:db8::1]:22 ",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hosts, err := tt.parse()
+			if err != nil {
+				t.Fatalf("parse failed: %v", err)
+			}
+			var got []string
+			for _, h := range hosts {
+				got = append(got, h.Link()+" "+strings.Join(h.Tags, ","))
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("got:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package logging
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"strings"
+)
+
+// Level orders log messages by severity; higher levels are more verbose
+type Level int
+
+const (
+	LevelError Level = iota
+	LevelWarn
+	LevelInfo
+	LevelDebug
+)
+
+var levelNames = []string{"error", "warn", "info", "debug"}
+
+func (l Level) String() string {
+	if l < LevelError || l > LevelDebug {
+		return fmt.Sprintf("level(%d)", int(l))
+	}
+	return levelNames[l]
+}
+
+// ParseLevel accepts error, warn(ing), info or debug, case-insensitively
+func ParseLevel(s string) (Level, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "error":
+		return LevelError, nil
+	case "warn", "warning":
+		return LevelWarn, nil
+	case "info":
+		return LevelInfo, nil
+	case "debug":
+		return LevelDebug, nil
+	default:
+		return LevelWarn, fmt.Errorf("unknown log level: %q", s)
+	}
+}
+
+var (
+	current = LevelWarn
+	logger  = log.New(io.Discard, "", log.LstdFlags|log.Lshortfile)
+)
+
+// Setup sends messages at or above level to w
+func Setup(w io.Writer, level Level) {
+	logger.SetOutput(w)
+	current = level
+}
+
+// Enabled reports whether messages at level are written
+func Enabled(level Level) bool {
+	return level <= current
+}
+
+func output(level Level, format string, args ...any) {
+	if !Enabled(level) {
+		return
+	}
+	// Skip output and the level helper so Lshortfile names the caller
+	logger.Output(3, strings.ToUpper(level.String())+": "+fmt.Sprintf(format, args...))
+}
+
+func Errorf(format string, args ...any) { output(LevelError, format, args...) }
+func Warnf(format string, args ...any)  { output(LevelWarn, format, args...) }
+func Infof(format string, args ...any)  { output(LevelInfo, format, args...) }
+func Debugf(format string, args ...any) { output(LevelDebug, format, args...) }
@@ -0,0 +1,79 @@
+package logging
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLevels(t *testing.T) {
+	var buf bytes.Buffer
+	Setup(&buf, LevelInfo)
+	defer Setup(&bytes.Buffer{}, LevelWarn)
+
+	Debugf("hidden %d", 1)
+	Infof("shown %d", 2)
+	Errorf("shown %d", 3)
+
+	out := buf.String()
+	if strings.Contains(out, "hidden") {
+		t.Errorf("debug message written at info level:\n%s", out)
+	}
+	if !strings.Contains(out, "INFO: shown 2") || !strings.Contains(out, "ERROR: shown 3") {
+		t.Errorf("expected info and error messages, got:\n%s", out)
+	}
+	if !strings.Contains(out, "logging_test.go") {
+		t.Errorf("expected caller file name in output, got:\n%s", out)
+	}
+
+	if level, err := ParseLevel("WARNING"); err != nil || level != LevelWarn {
+		t.Errorf("ParseLevel(WARNING) = %v, %v", level, err)
+	}
+	if _, err := ParseLevel("loud"); err == nil {
+		t.Errorf("expected error for unknown level")
+	}
+}
+
+func TestRotatingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", "sshlink.log")
+	r := NewRotatingFile(path, 10, 2)
+	defer r.Close()
+
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("log file created before first write")
+	}
+
+	for _, line := range []string{"first\n", "second\n", "third\n", "fourth\n"} {
+		if _, err := r.Write([]byte(line)); err != nil {
+			t.Fatalf("write failed: %v", err)
+		}
+	}
+
+	expected := map[string]string{
+		path:        "fourth\n",
+		path + ".1": "third\n",
+		path + ".2": "second\n",
+	}
+	for file, want := range expected {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatalf("reading %s: %v", file, err)
+		}
+		if string(content) != want {
+			t.Errorf("%s = %q, want %q", file, content, want)
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("expected only 2 backups to be kept")
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("log file permissions = %o, want 600", perm)
+	}
+}
@@ -1,19 +1,20 @@
 package main
 
 import (
+	"bytes"
 	"embed"
 	"flag"
 	"fmt"
-	"log"
 	"net/url"
 	"os"
-	"os/exec"
 	"os/user"
 	"path/filepath"
 	"runtime"
 	"strings"
 	"text/template"
+	"time"
 
+	"github.com/icanhazstring/sshlink/logging"
 	"github.com/icanhazstring/sshlink/terminals"
 )
 
@@ -22,6 +23,9 @@ var objcWrapperFS embed.FS
 
 var version = "dev"
 
+// lsregisterPath is the Launch Services tool used to (un)register the app bundle
+const lsregisterPath = "/System/Library/Frameworks/CoreServices.framework/Frameworks/LaunchServices.framework/Support/lsregister"
+
 var supportedDarwinTerminals = map[string][]string{
 	"terminal": {"-e"},
 	"iterm":    {},
@@ -36,25 +40,24 @@ var supportedLinuxTerminals = map[string][]string{
 var supportedWindowsTerminals = map[string][]string{}
 
 func main() {
-	// Set up logging to a file for debugging
-	setupDebugLogging()
-
-	// Debug: Log all arguments when launched
-	log.Printf("DEBUG: os.Args = %v", os.Args)
-	log.Printf("DEBUG: Number of args = %d", len(os.Args))
-	for i, arg := range os.Args {
-		log.Printf("DEBUG: arg[%d] = %q", i, arg)
-	}
-
 	var (
 		install   = flag.Bool("install", false, "Install sshlink URL handler")
 		uninstall = flag.Bool("uninstall", false, "Uninstall sshlink URL handler")
 		terminal  = flag.String("terminal", "terminal", "Terminal to use (terminal, iterm, warp, kitty, alacritty, wezterm)")
 		showVer   = flag.Bool("version", false, "Show version")
 		list      = flag.Bool("list", false, "List supported terminals")
+		verbose   = flag.Bool("v", false, "Write debug logs to the state directory")
 	)
 	flag.Parse()
 
+	setupLogging(*verbose)
+
+	// Log all arguments, URL handler launches differ between desktops
+	logging.Debugf("os.Args = %v", os.Args)
+	for i, arg := range os.Args {
+		logging.Debugf("arg[%d] = %q", i, arg)
+	}
+
 	if *showVer {
 		fmt.Printf("sshlink version %s\n", version)
 		return
@@ -81,78 +84,102 @@ func main() {
 
 	if *install {
 		if err := installHandler(*terminal); err != nil {
-			log.Fatalf("Installation failed: %v", err)
+			fatalf("Installation failed: %v", err)
 		}
 		return
 	}
 
 	if *uninstall {
 		if err := uninstallHandler(); err != nil {
-			log.Fatalf("Uninstallation failed: %v", err)
+			fatalf("Uninstallation failed: %v", err)
 		}
 		return
 	}
 
 	// Check if we have a URL argument
 	args := flag.Args()
-	log.Printf("DEBUG: flag.Args() = %v", args)
-	log.Printf("DEBUG: Number of remaining args = %d", len(args))
+	logging.Debugf("flag.Args() = %v", args)
 
 	if len(args) == 0 {
-		log.Printf("DEBUG: No URL arguments found, but app was launched")
-		log.Printf("DEBUG: This might be a URL handler launch with different argument format")
+		logging.Debugf("No URL arguments found, checking for a URL handler launch with a different argument format")
 
 		// Check if any argument looks like a URL
 		for i, arg := range os.Args {
-			if strings.HasPrefix(arg, "sshlink://") {
-				log.Printf("DEBUG: Found sshlink URL in arg[%d]: %s", i, arg)
+			if isLinkURL(arg) {
+				logging.Debugf("Found sshlink URL in arg[%d]: %s", i, arg)
 				urlString := arg
-				terminalType := *terminal
-				if *terminal == "terminal" {
-					if savedTerminal := readTerminalPreference(); savedTerminal != "" {
-						terminalType = savedTerminal
-					}
-				}
+				terminalType := resolveTerminalType(*terminal)
 
 				if err := handleURL(urlString, terminalType); err != nil {
-					log.Fatalf("Error: %v", err)
+					fatalf("Error: %v", err)
 				}
 				return
 			}
 		}
 
-		log.Printf("DEBUG: No sshlink:// URL found in any argument")
+		logging.Debugf("No sshlink:// URL found in any argument")
 
 		fmt.Fprintf(os.Stderr, "sshlink - SSH URL handler v%s\n\n", version)
 		fmt.Fprintf(os.Stderr, "Usage:\n")
-		fmt.Fprintf(os.Stderr, "  %s [options] <sshlink://host>\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s [options] <sshlink://host>\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s resolve <sshlink://host>\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s gen [-format list|markdown|html|json] [-ansible FILE|-csv FILE]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s page [-o sshlink.html] [-ansible FILE|-csv FILE]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s serve [-addr 127.0.0.1:8722]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s native-host -install -chrome-extension=ID\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s tunnels list|stop <pid|all>\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s audit tail|search -host PATTERN\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s recent | reopen <n>\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s fav add|list|remove\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s pick  # Fuzzy search history, favourites and ~/.ssh/config\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s doctor [-json]  # Check why links don't open\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s open [-dry-run] <sshlink://host>\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s install|uninstall [-system -prefix /usr/local]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s status [-json]  # Show what is installed where\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s upgrade | reinstall  # Point the handler at this binary\n\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "Options:\n")
 		flag.PrintDefaults()
 		fmt.Fprintf(os.Stderr, "\nExamples:\n")
 		fmt.Fprintf(os.Stderr, "  %s sshlink://192.168.1.1  # Handle SSH URL\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "  %s -install -terminal=iterm  # Install with iTerm\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "  %s -list  # Show supported terminals\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s resolve sshlink://prod-db-1  # Show settings from ~/.ssh/config\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s gen -format markdown  # Links for every ~/.ssh/config host\n", os.Args[0])
 		os.Exit(1)
 	}
 
+	if run, ok := subcommands[args[0]]; ok {
+		if err := run(args[1:]); err != nil {
+			logging.Errorf("%s failed: %v", args[0], err)
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	urlString := args[0]
-	log.Printf("DEBUG: Processing URL: %s", urlString)
 
 	// If launched as URL handler, try to read terminal preference
-	terminalType := *terminal
-	if *terminal == "terminal" { // default value, might be overridden by preferences
-		if savedTerminal := readTerminalPreference(); savedTerminal != "" {
-			terminalType = savedTerminal
-			log.Printf("DEBUG: Using saved terminal preference: %s", terminalType)
-		}
-	}
+	terminalType := resolveTerminalType(*terminal)
 
-	log.Printf("DEBUG: About to call handleURL with URL=%s, terminal=%s", urlString, terminalType)
+	logging.Debugf("Handling URL=%s, terminal=%s", urlString, terminalType)
 	if err := handleURL(urlString, terminalType); err != nil {
-		log.Fatalf("Error: %v", err)
+		fatalf("Error: %v", err)
 	}
 }
 
+// resolveTerminalType replaces the default -terminal value with the saved preference
+func resolveTerminalType(terminal string) string {
+	if terminal != "terminal" { // default value, might be overridden by preferences
+		return terminal
+	}
+	if savedTerminal := readTerminalPreference(); savedTerminal != "" {
+		logging.Debugf("Using saved terminal preference: %s", savedTerminal)
+		return savedTerminal
+	}
+	return terminal
+}
+
 func readTerminalPreference() string {
 	switch runtime.GOOS {
 	case "darwin":
@@ -164,8 +191,7 @@ func readTerminalPreference() string {
 		prefsPath := fmt.Sprintf("%s/Library/Preferences/com.icanhazstring.sshlink.plist", homeDir)
 
 		// Use defaults command to read preference
-		cmd := exec.Command("defaults", "read", prefsPath, "defaultTerminal")
-		output, err := cmd.Output()
+		output, err := commandOutput("defaults", "read", prefsPath, "defaultTerminal")
 		if err != nil {
 			return ""
 		}
@@ -173,31 +199,11 @@ func readTerminalPreference() string {
 		return strings.TrimSpace(string(output))
 
 	case "linux":
-		homeDir, err := os.UserHomeDir()
-		if err != nil {
-			return ""
-		}
-
-		prefsFile := filepath.Join(homeDir, ".config", "sshlink", "config")
-		content, err := os.ReadFile(prefsFile)
-		if err != nil {
-			return ""
-		}
-
-		// Parse simple key=value format
-		lines := strings.Split(string(content), "\n")
-		for _, line := range lines {
-			line = strings.TrimSpace(line)
-			if strings.HasPrefix(line, "terminal=") {
-				return strings.TrimPrefix(line, "terminal=")
-			}
-		}
+		return readConfigValue("terminal")
 
 	default:
 		return ""
 	}
-
-	return ""
 }
 
 func readShellPreference() string {
@@ -205,64 +211,237 @@ func readShellPreference() string {
 		return "/bin/bash" // fallback for non-Linux
 	}
 
-	homeDir, err := os.UserHomeDir()
+	if shell := readConfigValue("shell"); shell != "" {
+		return shell
+	}
+
+	return detectUserShell() // fallback to detection
+}
+
+// session describes what a handled link opens in the terminal
+type session struct {
+	label   string   // e.g. "ssh" or "sftp", used for the tab title
+	name    string   // what the session connects to, for titles and messages
+	target  Target   // destination the session connects to
+	command []string // nil opens an interactive session to target
+	// transport is used when command is nil: ssh, mosh or et
+	transport string
+	// background runs command without a terminal, e.g. for tunnels
+	background bool
+	// preflight is the host:port to probe before launching, "" to skip
+	preflight string
+	// link reopens the session's host, recorded in the history
+	link string
+	// dryRun prints what would be started instead of starting it
+	dryRun bool
+}
+
+func handleURL(urlString, terminalType string) error {
+	return openLink(urlString, terminalType, dryRunEnabled())
+}
+
+// openLink parses and opens a link. With dryRun the whole pipeline runs,
+// but the commands are printed instead of started.
+func openLink(urlString, terminalType string, dryRun bool) error {
+	record := auditRecord{Time: time.Now(), URL: redactURL(urlString)}
+
+	s, err := parseLink(urlString)
 	if err != nil {
-		return "/bin/bash"
+		record.Decision, record.Reason, record.Outcome = "rejected", err.Error(), "rejected"
+		writeAudit(record)
+		return notifyError("sshlink: link rejected", err)
 	}
+	record.Decision = "allowed"
+	record.Target, record.Host = s.name, s.target.Host
 
-	prefsFile := filepath.Join(homeDir, ".config", "sshlink", "config")
-	content, err := os.ReadFile(prefsFile)
+	s.dryRun = dryRun
+
+	fmt.Printf("🚀 Opening %s session to: %s\n", s.label, s.name)
+	record.Terminal, err = executeSSH(s, terminalType)
+	record.Outcome = "opened"
+	if dryRun {
+		record.Outcome = "dry-run"
+	}
 	if err != nil {
-		return detectUserShell() // fallback to detection
+		record.Outcome, record.Error = "failed", err.Error()
 	}
+	writeAudit(record)
+	return err
+}
 
-	// Parse simple key=value format
-	lines := strings.Split(string(content), "\n")
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if strings.HasPrefix(line, "shell=") {
-			return strings.TrimPrefix(line, "shell=")
-		}
+// parseLink validates a link and turns it into the session it opens
+func parseLink(urlString string) (session, error) {
+	u, scheme, err := parseLinkURL(urlString)
+	if err != nil {
+		return session{}, err
 	}
 
-	return detectUserShell() // fallback to detection
+	p, err := loadPolicy()
+	if err != nil {
+		return session{}, fmt.Errorf("invalid policy, refusing to open links: %v", err)
+	}
+
+	s, err := buildSession(u, scheme)
+	if err != nil {
+		return session{}, err
+	}
+
+	resolvedHost := s.target.Host
+	if !isContainerLink(u) {
+		resolvedHost = resolveTarget(s.target, loadSSHConfig()).Host
+	}
+	if err := p.check(u, s.target.Host, resolvedHost); err != nil {
+		return session{}, err
+	}
+	s.link = historyLink(u)
+	return s, nil
 }
 
-func handleURL(urlString, terminalType string) error {
+// parseLinkURL parses a link and looks up its scheme
+func parseLinkURL(urlString string) (*url.URL, linkScheme, error) {
 	u, err := url.Parse(urlString)
 	if err != nil {
-		return fmt.Errorf("invalid URL: %v", err)
+		return nil, linkScheme{}, fmt.Errorf("invalid URL: %v", err)
 	}
 
-	if u.Scheme != "sshlink" {
-		return fmt.Errorf("unsupported scheme: %s", u.Scheme)
+	scheme, ok := lookupScheme(u.Scheme)
+	if !ok {
+		return nil, linkScheme{}, fmt.Errorf("unsupported scheme: %s", u.Scheme)
 	}
 
 	if u.Host == "" {
-		return fmt.Errorf("no target specified")
+		return nil, linkScheme{}, fmt.Errorf("no target specified")
+	}
+	return u, scheme, nil
+}
+
+// buildSession validates the link and works out the command it opens
+func buildSession(u *url.URL, scheme linkScheme) (session, error) {
+	if scheme.name == "sshlink" && isContainerLink(u) {
+		return containerSession(u)
+	}
+
+	target := parseTarget(u)
+	err := validateTarget(target)
+	if err != nil {
+		return session{}, err
+	}
+
+	sshConfig := loadSSHConfig()
+	target = applySSHConfig(target, sshConfig)
+	s := session{label: scheme.label, name: target.String(), target: target}
+
+	query := u.Query()
+	if preflightEnabled(query.Get("preflight")) {
+		s.preflight = preflightAddress(target, sshConfig)
+	}
+	if forwards := query["forward"]; len(forwards) > 0 {
+		if scheme.name != "sshlink" {
+			return session{}, fmt.Errorf("forward is only supported for sshlink:// links")
+		}
+		background := query.Get("background") == "1" || query.Get("background") == "true" ||
+			readConfigValue("tunnel_mode") == "background"
+		return tunnelSession(s, forwards, background)
+	}
+
+	if dir, cmd := query.Get("cd"), query.Get("cmd"); dir != "" || cmd != "" {
+		if scheme.name != "sshlink" {
+			return session{}, fmt.Errorf("cd and cmd are only supported for sshlink:// links")
+		}
+		return remoteSession(s, dir, cmd)
 	}
 
-	target := strings.Replace(urlString, "sshlink://", "", 1)
+	if scheme.command != nil {
+		command, err := scheme.command(target)
+		if err != nil {
+			return session{}, err
+		}
+		s.command = command
+		return s, nil
+	}
 
-	fmt.Printf("🚀 Opening SSH connection to: %s\n", target)
-	return executeSSH(target, terminalType)
+	s.transport = scheme.transport
+	if s.transport == "" {
+		if s.transport, err = transportFor(target.Host, query.Get("transport")); err != nil {
+			return session{}, err
+		}
+	}
+	return s, nil
 }
 
-func executeSSH(host, terminalType string) error {
+// executeSSH starts the session and returns the terminal it chose, "" when
+// it failed before picking one
+func executeSSH(s session, terminalType string) (string, error) {
+	if s.preflight != "" && !s.dryRun {
+		if err := preflight(s.preflight, preflightTimeout()); err != nil {
+			return "", notifyError("sshlink: host unreachable", err)
+		}
+	}
+
+	if s.background {
+		if s.dryRun {
+			return "background", printDryRun(s, "background process", "", []terminals.Invocation{
+				{Name: s.command[0], Args: s.command[1:]},
+			})
+		}
+		if err := startTunnel(s); err != nil {
+			return "background", notifyError("sshlink: launch failed", err)
+		}
+		recordHistory(s)
+		return "background", nil
+	}
+
 	// For Linux, set the user shell in the factory before creating terminal
 	if runtime.GOOS == "linux" {
 		userShell := readShellPreference()
 		terminals.SetUserShell(userShell)
-		log.Printf("DEBUG: Set user shell to: %s", userShell)
+		logging.Debugf("Set user shell to: %s", userShell)
 	}
 
-	terminal, err := terminals.CreateTerminal(terminalType)
+	terminal, err := terminals.CreateTerminal(resolveTerminalType(terminalType))
 	if err != nil {
-		return err
+		return "", notifyError("sshlink: unknown terminal", err)
 	}
 
-	fmt.Printf("🚀 Opening SSH connection to: %s using %s\n", host, terminal.Name())
-	return terminal.Open(host)
+	// Interactive sessions over mosh or et, falling back to plain ssh
+	if s.command == nil && s.transport != "" && s.transport != "ssh" {
+		s.command = transportCommand(s.transport, s.target)
+		s.label = s.transport
+		if s.command == nil {
+			s.label = "ssh"
+		}
+	}
+	sshSession := s.command == nil
+	if sshSession {
+		s.command = s.target.sshCommand()
+	}
+
+	// Title the tab after the target and pick a profile by host pattern,
+	// e.g. profile=prod-*:Production in the config file
+	terminal.SetTitle(fmt.Sprintf("%s: %s", s.label, s.name))
+	profile := readPatternValue("profile", s.target.Host)
+	if profile != "" {
+		terminal.SetProfile(profile)
+		logging.Debugf("Using terminal profile: %s", profile)
+	}
+
+	// The backend's own plan of the argv the launch below runs
+	if s.dryRun {
+		return terminal.Name(), printDryRun(s, terminal.Name(), profile, terminal.Plan(s.command))
+	}
+
+	if sshSession {
+		fmt.Printf("🚀 Opening SSH connection to: %s using %s\n", s.name, terminal.Name())
+	} else {
+		logging.Debugf("Running %q in %s", s.command, terminal.Name())
+		fmt.Printf("🚀 Opening %s to: %s using %s\n", s.label, s.name, terminal.Name())
+	}
+	err = terminal.Exec(s.command)
+	if err != nil {
+		return terminal.Name(), notifyError("sshlink: launch failed", fmt.Errorf("failed to open %s: %v", terminal.Name(), err))
+	}
+	recordHistory(s)
+	return terminal.Name(), nil
 }
 
 func installHandler(terminalType string) error {
@@ -272,7 +451,7 @@ func installHandler(terminalType string) error {
 	if runtime.GOOS == "linux" {
 		userShell := readShellPreference()
 		terminals.SetUserShell(userShell)
-		log.Printf("DEBUG: Set user shell to: %s", userShell)
+		logging.Debugf("Set user shell to: %s", userShell)
 	}
 
 	// Validate terminal type by trying to create it
@@ -293,21 +472,64 @@ func installHandler(terminalType string) error {
 	}
 }
 
-func setupDebugLogging() {
-	homeDir, err := os.UserHomeDir()
-	if err != nil {
+// logMaxSize and logBackups bound the log file, rotated to sshlink.log.1 ... .3
+const (
+	logMaxSize = 1024 * 1024
+	logBackups = 3
+)
+
+// logPath returns the log file inside the state directory
+func logPath() string {
+	dir := stateDir()
+	if dir == "" {
+		return ""
+	}
+	return filepath.Join(dir, "sshlink.log")
+}
+
+// setupLogging enables the leveled log. Only warnings and errors are written
+// unless -v, $SSHLINK_LOG or log_level= in the config ask for more.
+func setupLogging(verbose bool) {
+	level := logging.LevelWarn
+	value := os.Getenv("SSHLINK_LOG")
+	if value == "" {
+		value = readConfigValue("log_level")
+	}
+	if value != "" {
+		parsed, err := logging.ParseLevel(value)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "⚠️  %v, using %s\n", err, level)
+		} else {
+			level = parsed
+		}
+	}
+	if verbose {
+		level = logging.LevelDebug
+	}
+
+	path := logPath()
+	if path == "" {
 		return
 	}
+	logging.Setup(logging.NewRotatingFile(path, logMaxSize, logBackups), level)
+	logging.Debugf("=== sshlink %s started ===", version)
+}
 
-	logPath := fmt.Sprintf("%s/sshlink-debug.log", homeDir)
-	logFile, err := os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+// legacyLogPath is the world-readable debug log older versions wrote on
+// every run, including full link URLs
+func legacyLogPath() string {
+	homeDir, err := os.UserHomeDir()
 	if err != nil {
-		return
+		return ""
 	}
+	return filepath.Join(homeDir, "sshlink-debug.log")
+}
 
-	log.SetOutput(logFile)
-	log.SetFlags(log.LstdFlags | log.Lshortfile)
-	log.Printf("=== SSHLink started ===")
+// fatalf logs and reports an error, then exits
+func fatalf(format string, args ...any) {
+	logging.Errorf(format, args...)
+	fmt.Fprintf(os.Stderr, format+"\n", args...)
+	os.Exit(1)
 }
 
 func installHandlerMacOS(terminalName string) error {
@@ -334,84 +556,26 @@ func installHandlerMacOS(terminalName string) error {
 		return fmt.Errorf("failed to create resources directory: %v", err)
 	}
 
-	// Copy executable as SSHLink-real
+	// Copy executable as SSHLink-real, replacing an existing copy in one
+	// step so a link opened meanwhile never runs a half-written binary
 	realExecPath := fmt.Sprintf("%s/SSHLink-real", macOSPath)
-	if err := copyFile(execPath, realExecPath); err != nil {
-		return fmt.Errorf("failed to copy executable: %v", err)
-	}
-
-	if err := os.Chmod(realExecPath, 0755); err != nil {
-		return fmt.Errorf("failed to make executable: %v", err)
-	}
-
-	// Read Objective-C wrapper source from embedded file
-	objcSource, err := getObjectiveCWrapper()
+	execContent, err := os.ReadFile(execPath)
 	if err != nil {
-		return fmt.Errorf("failed to get Objective-C wrapper source: %v", err)
+		return fmt.Errorf("failed to read executable: %v", err)
 	}
-
-	// Write Objective-C source to temporary file
-	objcSourcePath := fmt.Sprintf("%s/SSHLinkHandler.m", resourcesPath)
-	if err := os.WriteFile(objcSourcePath, []byte(objcSource), 0644); err != nil {
-		return fmt.Errorf("failed to create Objective-C source: %v", err)
+	if err := writeFileAtomic(realExecPath, execContent, 0755); err != nil {
+		return fmt.Errorf("failed to copy executable: %v", err)
 	}
 
 	// Compile the Objective-C handler
 	objcBinaryPath := fmt.Sprintf("%s/SSHLink", macOSPath)
-	fmt.Println("🔨 Compiling Objective-C URL handler...")
-
-	cmd := exec.Command("clang",
-		"-framework", "Foundation",
-		"-framework", "AppKit",
-		"-o", objcBinaryPath,
-		objcSourcePath)
-
-	if output, err := cmd.CombinedOutput(); err != nil {
-		return fmt.Errorf("failed to compile Objective-C handler: %v\nOutput: %s", err, output)
+	if err := compileWrapper(objcBinaryPath, resourcesPath); err != nil {
+		return err
 	}
 
-	// Remove the source file after compilation
-	os.Remove(objcSourcePath)
-
 	// Create Info.plist
 	infoPlistPath := fmt.Sprintf("%s/Info.plist", contentsPath)
-	infoPlistContent := fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
-<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
-<plist version="1.0">
-<dict>
-	<key>CFBundleExecutable</key>
-	<string>SSHLink</string>
-	<key>CFBundleIdentifier</key>
-	<string>com.icanhazstring.sshlink</string>
-	<key>CFBundleName</key>
-	<string>SSHLink</string>
-	<key>CFBundleDisplayName</key>
-	<string>SSHLink</string>
-	<key>CFBundleVersion</key>
-	<string>%s</string>
-	<key>CFBundleShortVersionString</key>
-	<string>%s</string>
-	<key>CFBundlePackageType</key>
-	<string>APPL</string>
-	<key>CFBundleURLTypes</key>
-	<array>
-		<dict>
-			<key>CFBundleURLName</key>
-			<string>SSH Link Protocol</string>
-			<key>CFBundleURLSchemes</key>
-			<array>
-				<string>sshlink</string>
-			</array>
-		</dict>
-	</array>
-	<key>NSHighResolutionCapable</key>
-	<true/>
-	<key>LSUIElement</key>
-	<true/>
-</dict>
-</plist>`, version, version)
-
-	if err := os.WriteFile(infoPlistPath, []byte(infoPlistContent), 0644); err != nil {
+	if err := os.WriteFile(infoPlistPath, []byte(infoPlist()), 0644); err != nil {
 		return fmt.Errorf("failed to create Info.plist: %v", err)
 	}
 
@@ -422,8 +586,7 @@ func installHandlerMacOS(terminalName string) error {
 	fmt.Println("🔨 Compiled Objective-C Apple Event handler")
 	fmt.Println("🔄 Registering with macOS Launch Services...")
 
-	cmd = exec.Command("/System/Library/Frameworks/CoreServices.framework/Frameworks/LaunchServices.framework/Support/lsregister", "-f", appPath)
-	if err := cmd.Run(); err != nil {
+	if err := runCommand(lsregisterPath, "-f", appPath); err != nil {
 		return fmt.Errorf("failed to register app with Launch Services: %v", err)
 	}
 
@@ -446,7 +609,7 @@ func installHandlerMacOS(terminalName string) error {
 	fmt.Printf("   App bundle: %s\n", appPath)
 	fmt.Printf("   Default terminal: %s\n", terminalName)
 	fmt.Printf("   You can now use sshlink:// URLs in your browser\n")
-	fmt.Printf("   Debug logs: ~/sshlink-debug.log\n")
+	fmt.Printf("   Logs: %s (more detail with log_level=debug in the config)\n", logPath())
 	fmt.Printf("\n🧪 Test installation:\n")
 	fmt.Printf("   Manual test: %s sshlink://test@example.com\n", realExecPath)
 	fmt.Printf("   Browser test: Click any sshlink:// URL\n")
@@ -454,10 +617,19 @@ func installHandlerMacOS(terminalName string) error {
 	return nil
 }
 
+// plistSchemes renders the CFBundleURLSchemes entries for every registered scheme
+func plistSchemes() string {
+	var entries []string
+	for _, scheme := range schemeNames() {
+		entries = append(entries, fmt.Sprintf("\t\t\t\t<string>%s</string>", scheme))
+	}
+	return strings.Join(entries, "\n")
+}
+
 func installHandlerLinux(terminalName string) error {
-	usr, err := user.Current()
+	homeDir, err := os.UserHomeDir()
 	if err != nil {
-		return fmt.Errorf("failed to get current user: %v", err)
+		return fmt.Errorf("failed to get home directory: %v", err)
 	}
 
 	// Get the absolute path to the current executable
@@ -470,52 +642,90 @@ func installHandlerLinux(terminalName string) error {
 	userShell := detectUserShell()
 	fmt.Printf("🐚 Detected shell: %s\n", userShell)
 
-	// Create directories if they don't exist
-	appDir := filepath.Join(usr.HomeDir, ".local", "share", "applications")
-	if err := os.MkdirAll(appDir, 0755); err != nil {
-		return fmt.Errorf("failed to create applications directory: %v", err)
+	st, err := loadInstallState()
+	if err != nil {
+		return err
 	}
+	_, statErr := os.Stat(installStatePath())
+	stateExists := statErr == nil
 
 	fmt.Printf("📦 Installing sshlink handler for Linux...\n")
 	fmt.Printf("   Terminal: %s\n", terminalName)
 	fmt.Printf("   Executable: %s\n", execPath)
 
 	// Create the desktop file
-	desktopFile := filepath.Join(appDir, "sshlink.desktop")
-	if err := createDesktopFile(desktopFile, execPath, terminalName); err != nil {
+	desktopFile := filepath.Join(homeDir, ".local", "share", "applications", "sshlink.desktop")
+	desktopContent, err := desktopFileContent(execPath, terminalName)
+	if err != nil {
+		return err
+	}
+	desktopChanged, err := st.writeFile(desktopFile, desktopContent, 0755)
+	if err != nil {
 		return fmt.Errorf("failed to create desktop file: %v", err)
 	}
-
-	fmt.Printf("📄 Created desktop file: %s\n", desktopFile)
-
-	// Save terminal preference and shell
-	prefsDir := filepath.Join(usr.HomeDir, ".config", "sshlink")
-	if err := os.MkdirAll(prefsDir, 0755); err != nil {
-		return fmt.Errorf("failed to create config directory: %v", err)
+	if desktopChanged {
+		fmt.Printf("📄 Created desktop file: %s\n", desktopFile)
 	}
 
-	prefsFile := filepath.Join(prefsDir, "config")
-	prefsContent := fmt.Sprintf("terminal=%s\nshell=%s\n", terminalName, userShell)
-	if err := os.WriteFile(prefsFile, []byte(prefsContent), 0644); err != nil {
+	// Save terminal preference and shell, keeping the rest of the config
+	prefsFile := filepath.Join(homeDir, ".config", "sshlink", "config")
+	existing, err := os.ReadFile(prefsFile)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read config: %v", err)
+	}
+	prefsContent := setConfigValue(setConfigValue(string(existing), "terminal", terminalName), "shell", userShell)
+	prefsChanged, err := st.writeFile(prefsFile, []byte(prefsContent), 0644)
+	if err != nil {
 		return fmt.Errorf("failed to save terminal preference: %v", err)
 	}
+	if prefsChanged {
+		fmt.Printf("⚙️  Saved preferences: %s\n", prefsFile)
+		fmt.Printf("   Terminal: %s\n", terminalName)
+		fmt.Printf("   Shell: %s\n", userShell)
+	}
 
-	fmt.Printf("⚙️  Saved preferences: %s\n", prefsFile)
-	fmt.Printf("   Terminal: %s\n", terminalName)
-	fmt.Printf("   Shell: %s\n", userShell)
+	// Remember the previous handlers so uninstall can restore them
+	registered := true
+	for _, scheme := range schemeNames() {
+		current := queryDefaultHandler(scheme)
+		if current == "sshlink.desktop" {
+			current = "" // installed before sshlink tracked handlers
+		} else {
+			registered = false
+		}
+		if _, recorded := st.Handlers[scheme]; !recorded {
+			st.Handlers[scheme] = current
+		}
+	}
+
+	if !desktopChanged && !prefsChanged && registered {
+		if !stateExists {
+			st.Version = version
+			if err := st.save(); err != nil {
+				return fmt.Errorf("failed to save install state: %v", err)
+			}
+		}
+		fmt.Println("✅ SSHLink is already installed, nothing to change")
+		return nil
+	}
 
 	// Register the protocol handler
 	if err := registerProtocolHandler(); err != nil {
 		return fmt.Errorf("failed to register protocol handler: %v", err)
 	}
 
+	st.Version = version
+	if err := st.save(); err != nil {
+		return fmt.Errorf("failed to save install state: %v", err)
+	}
+
 	fmt.Printf("✅ SSHLink installed successfully for Linux!\n")
 	fmt.Printf("   Desktop file: %s\n", desktopFile)
 	fmt.Printf("   Default terminal: %s\n", terminalName)
 	fmt.Printf("   Default shell: %s\n", userShell)
 	fmt.Printf("   Config: %s\n", prefsFile)
 	fmt.Printf("   You can now use sshlink:// URLs in your browser\n")
-	fmt.Printf("   Debug logs: ~/sshlink-debug.log\n")
+	fmt.Printf("   Logs: %s (more detail with log_level=debug in the config)\n", logPath())
 	fmt.Printf("\n🧪 Test installation:\n")
 	fmt.Printf("   Manual test: %s sshlink://test@example.com\n", execPath)
 	fmt.Printf("   Browser test: Click any sshlink:// URL\n")
@@ -523,6 +733,15 @@ func installHandlerLinux(terminalName string) error {
 	return nil
 }
 
+// queryDefaultHandler returns the desktop file handling scheme, "" if none
+func queryDefaultHandler(scheme string) string {
+	output, err := commandOutput("xdg-mime", "query", "default", "x-scheme-handler/"+scheme)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(output))
+}
+
 func registerProtocolHandler() error {
 	homeDir := os.Getenv("HOME")
 	if homeDir == "" {
@@ -537,75 +756,68 @@ func registerProtocolHandler() error {
 
 	fmt.Println("🔄 Updating desktop database...")
 	// Update desktop database
-	updateDatabaseCommand := exec.Command("update-desktop-database", appDir)
-	if err := updateDatabaseCommand.Run(); err != nil {
+	if err := runCommand("update-desktop-database", appDir); err != nil {
 		fmt.Printf("⚠️  Warning: Could not update desktop database: %v\n", err)
 		// Don't return error as this is not critical
 	}
 
 	fmt.Println("🔗 Registering protocol handler...")
-	// Register with xdg-mime
-	registerCommand := exec.Command("xdg-mime", "default", "sshlink.desktop", "x-scheme-handler/sshlink")
-	if err := registerCommand.Run(); err != nil {
-		return fmt.Errorf("failed to register with xdg-mime: %v (make sure xdg-utils is installed)", err)
+	// Register every scheme with xdg-mime
+	for _, scheme := range schemeNames() {
+		mimeType := "x-scheme-handler/" + scheme
+		if err := runCommand("xdg-mime", "default", "sshlink.desktop", mimeType); err != nil {
+			return fmt.Errorf("failed to register %s with xdg-mime: %v (make sure xdg-utils is installed)", scheme, err)
+		}
 	}
 
 	// Verify registration
 	fmt.Println("✓ Verifying protocol registration...")
-	verifyCommand := exec.Command("xdg-mime", "query", "default", "x-scheme-handler/sshlink")
-	if output, err := verifyCommand.Output(); err == nil {
-		result := strings.TrimSpace(string(output))
-		if result == "sshlink.desktop" {
-			fmt.Println("✓ Protocol handler registered successfully")
-		} else {
-			fmt.Printf("⚠️  Warning: Expected 'sshlink.desktop', got '%s'\n", result)
+	for _, scheme := range schemeNames() {
+		if output, err := commandOutput("xdg-mime", "query", "default", "x-scheme-handler/"+scheme); err == nil {
+			result := strings.TrimSpace(string(output))
+			if result == "sshlink.desktop" {
+				fmt.Printf("✓ Protocol handler registered successfully for %s://\n", scheme)
+			} else {
+				fmt.Printf("⚠️  Warning: Expected 'sshlink.desktop' for %s://, got '%s'\n", scheme, result)
+			}
 		}
 	}
 
 	return nil
 }
 
-func createDesktopFile(filePath, execPath, terminalName string) error {
+func desktopFileContent(execPath, terminalName string) ([]byte, error) {
 	desktopTemplate := `[Desktop Entry]
 Type=Application
 Name=SSH Link Handler
-Comment=Handle sshlink:// URLs
+Comment=Handle sshlink:// and related URLs
 Exec={{.ExecPath}} -terminal={{.Terminal}} %u
 Icon=utilities-terminal
 StartupNotify=false
 NoDisplay=true
-MimeType=x-scheme-handler/sshlink;
+MimeType={{range .Schemes}}x-scheme-handler/{{.}};{{end}}
 Categories=Network;
 Terminal=false
 `
 
 	tmpl, err := template.New("desktop").Parse(desktopTemplate)
 	if err != nil {
-		return fmt.Errorf("failed to parse desktop template: %v", err)
+		return nil, fmt.Errorf("failed to parse desktop template: %v", err)
 	}
 
-	file, err := os.Create(filePath)
-	if err != nil {
-		return fmt.Errorf("failed to create desktop file: %v", err)
-	}
-	defer file.Close()
-
-	if err := tmpl.Execute(file, struct {
+	var content bytes.Buffer
+	if err := tmpl.Execute(&content, struct {
 		ExecPath string
 		Terminal string
+		Schemes  []string
 	}{
 		ExecPath: execPath,
 		Terminal: terminalName,
+		Schemes:  schemeNames(),
 	}); err != nil {
-		return fmt.Errorf("failed to write desktop file: %v", err)
-	}
-
-	// Make the desktop file executable
-	if err := os.Chmod(filePath, 0755); err != nil {
-		return fmt.Errorf("failed to make desktop file executable: %v", err)
+		return nil, fmt.Errorf("failed to render desktop file: %v", err)
 	}
-
-	return nil
+	return content.Bytes(), nil
 }
 
 func installHandlerWindows(terminalName string) error {
@@ -615,23 +827,6 @@ func installHandlerWindows(terminalName string) error {
 	return nil
 }
 
-func copyFile(src, dst string) error {
-	sourceFile, err := os.Open(src)
-	if err != nil {
-		return err
-	}
-	defer sourceFile.Close()
-
-	destFile, err := os.Create(dst)
-	if err != nil {
-		return err
-	}
-	defer destFile.Close()
-
-	_, err = destFile.ReadFrom(sourceFile)
-	return err
-}
-
 func uninstallHandler() error {
 	fmt.Printf("Uninstalling sshlink handler on %s...\n", runtime.GOOS)
 
@@ -649,43 +844,60 @@ func uninstallHandler() error {
 }
 
 func uninstallHandlerLinux() error {
-	usr, err := user.Current()
+	homeDir, err := os.UserHomeDir()
 	if err != nil {
-		return fmt.Errorf("failed to get current user: %v", err)
+		return fmt.Errorf("failed to get home directory: %v", err)
 	}
 
 	fmt.Println("🗑️  Uninstalling sshlink handler for Linux...")
 
-	// Remove desktop file
-	desktopFile := filepath.Join(usr.HomeDir, ".local", "share", "applications", "sshlink.desktop")
-	if _, err := os.Stat(desktopFile); err == nil {
-		if err := os.Remove(desktopFile); err != nil {
-			return fmt.Errorf("failed to remove desktop file: %v", err)
-		}
-		fmt.Printf("🗑️  Removed desktop file: %s\n", desktopFile)
+	st, err := loadInstallState()
+	if err != nil {
+		return err
 	}
 
-	// Remove config directory
-	configDir := filepath.Join(usr.HomeDir, ".config", "sshlink")
-	if _, err := os.Stat(configDir); err == nil {
-		if err := os.RemoveAll(configDir); err != nil {
-			return fmt.Errorf("failed to remove config directory: %v", err)
+	if len(st.Backups) > 0 {
+		// Put back the files that were there before install
+		if err := st.restoreFiles(); err != nil {
+			return err
+		}
+	} else {
+		// Installed before sshlink kept install state: remove what it created
+		desktopFile := filepath.Join(homeDir, ".local", "share", "applications", "sshlink.desktop")
+		if _, err := os.Stat(desktopFile); err == nil {
+			if err := os.Remove(desktopFile); err != nil {
+				return fmt.Errorf("failed to remove desktop file: %v", err)
+			}
+			fmt.Printf("🗑️  Removed desktop file: %s\n", desktopFile)
+		}
+
+		// Only the config file: favourites, the serve token and the
+		// native host launcher live next to it
+		configFile := configPath()
+		if err := os.Remove(configFile); err == nil {
+			fmt.Printf("🗑️  Removed config: %s\n", configFile)
+		} else if !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove config: %v", err)
 		}
-		fmt.Printf("🗑️  Removed config: %s\n", configDir)
+		// Fails, as intended, while anything else is left in it
+		os.Remove(filepath.Dir(configFile))
+	}
+
+	fmt.Println("🔗 Restoring previous protocol handlers...")
+	if err := restoreHandlers(st); err != nil {
+		return err
 	}
 
 	// Update desktop database
-	appDir := filepath.Join(usr.HomeDir, ".local", "share", "applications")
+	appDir := filepath.Join(homeDir, ".local", "share", "applications")
 	fmt.Println("🔄 Updating desktop database...")
-	updateDatabaseCommand := exec.Command("update-desktop-database", appDir)
-	if err := updateDatabaseCommand.Run(); err != nil {
+	if err := runCommand("update-desktop-database", appDir); err != nil {
 		fmt.Printf("⚠️  Warning: Could not update desktop database: %v\n", err)
 	}
 
-	// Try to unregister the protocol handler
-	fmt.Println("🔗 Attempting to unregister protocol handler...")
-	// Note: There's no direct way to "unregister" with xdg-mime,
-	// but removing the desktop file and updating the database should be sufficient
+	if err := st.remove(); err != nil {
+		return fmt.Errorf("failed to remove install state: %v", err)
+	}
 
 	fmt.Println("✅ SSHLink uninstalled successfully from Linux!")
 	fmt.Println("   Note: You may need to restart your browser for changes to take effect")
@@ -693,6 +905,52 @@ func uninstallHandlerLinux() error {
 	return nil
 }
 
+// restoreHandlers hands every scheme still pointing at sshlink back to its
+// previous handler. xdg-mime can't unset a default, so schemes without a
+// previous handler (or when xdg-mime fails) are edited in mimeapps.list.
+func restoreHandlers(st *installState) error {
+	var unset []string
+	for _, scheme := range schemeNames() {
+		if current := queryDefaultHandler(scheme); current != "" && current != "sshlink.desktop" {
+			continue // changed by the user since install
+		}
+
+		previous := st.Handlers[scheme]
+		if previous != "" {
+			if err := runCommand("xdg-mime", "default", previous, "x-scheme-handler/"+scheme); err == nil {
+				fmt.Printf("↩️  %s:// handled by %s again\n", scheme, previous)
+				continue
+			}
+		}
+		unset = append(unset, scheme)
+	}
+	if len(unset) == 0 {
+		return nil
+	}
+
+	mimeApps := mimeAppsPath()
+	content, err := os.ReadFile(mimeApps)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %v", mimeApps, err)
+	}
+
+	edited := string(content)
+	for _, scheme := range unset {
+		edited = setMimeDefault(edited, "x-scheme-handler/"+scheme, st.Handlers[scheme])
+	}
+	if edited == string(content) {
+		return nil
+	}
+	if err := writeFileAtomic(mimeApps, []byte(edited), 0644); err != nil {
+		return fmt.Errorf("failed to update %s: %v", mimeApps, err)
+	}
+	fmt.Printf("🔗 Updated %s\n", mimeApps)
+	return nil
+}
+
 func uninstallHandlerMacOS() error {
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
@@ -721,8 +979,7 @@ func uninstallHandlerMacOS() error {
 
 	// Refresh Launch Services database
 	fmt.Println("🔄 Refreshing macOS Launch Services...")
-	cmd := exec.Command("/System/Library/Frameworks/CoreServices.framework/Frameworks/LaunchServices.framework/Support/lsregister", "-kill", "-r", "-domain", "local", "-domain", "user")
-	if err := cmd.Run(); err != nil {
+	if err := runCommand(lsregisterPath, "-kill", "-r", "-domain", "local", "-domain", "user"); err != nil {
 		fmt.Printf("⚠️  Warning: failed to refresh Launch Services: %v\n", err)
 	}
 
@@ -730,6 +987,76 @@ func uninstallHandlerMacOS() error {
 	return nil
 }
 
+// infoPlist returns the app bundle's Info.plist for the running version
+func infoPlist() string {
+	return fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>CFBundleExecutable</key>
+	<string>SSHLink</string>
+	<key>CFBundleIdentifier</key>
+	<string>com.icanhazstring.sshlink</string>
+	<key>CFBundleName</key>
+	<string>SSHLink</string>
+	<key>CFBundleDisplayName</key>
+	<string>SSHLink</string>
+	<key>CFBundleVersion</key>
+	<string>%s</string>
+	<key>CFBundleShortVersionString</key>
+	<string>%s</string>
+	<key>CFBundlePackageType</key>
+	<string>APPL</string>
+	<key>CFBundleURLTypes</key>
+	<array>
+		<dict>
+			<key>CFBundleURLName</key>
+			<string>SSH Link Protocol</string>
+			<key>CFBundleURLSchemes</key>
+			<array>
+%s
+			</array>
+		</dict>
+	</array>
+	<key>NSHighResolutionCapable</key>
+	<true/>
+	<key>LSUIElement</key>
+	<true/>
+</dict>
+</plist>`, version, version, plistSchemes())
+}
+
+// compileWrapper builds the Objective-C URL handler into objcBinaryPath.
+// It is compiled next to it and renamed into place, so a failed build
+// leaves a working handler behind.
+func compileWrapper(objcBinaryPath, resourcesPath string) error {
+	// Read Objective-C wrapper source from embedded file
+	objcSource, err := getObjectiveCWrapper()
+	if err != nil {
+		return fmt.Errorf("failed to get Objective-C wrapper source: %v", err)
+	}
+
+	// Write Objective-C source to temporary file
+	objcSourcePath := fmt.Sprintf("%s/SSHLinkHandler.m", resourcesPath)
+	if err := os.WriteFile(objcSourcePath, []byte(objcSource), 0644); err != nil {
+		return fmt.Errorf("failed to create Objective-C source: %v", err)
+	}
+	// Remove the source file after compilation
+	defer os.Remove(objcSourcePath)
+
+	fmt.Println("🔨 Compiling Objective-C URL handler...")
+	buildPath := objcBinaryPath + ".new"
+	if _, err := commandOutput("clang",
+		"-framework", "Foundation",
+		"-framework", "AppKit",
+		"-o", buildPath,
+		objcSourcePath); err != nil {
+		os.Remove(buildPath)
+		return fmt.Errorf("failed to compile Objective-C handler: %v", err)
+	}
+	return os.Rename(buildPath, objcBinaryPath)
+}
+
 func getObjectiveCWrapper() (string, error) {
 	content, err := objcWrapperFS.ReadFile("wrapper/sshlink_handler.m")
 	if err != nil {
@@ -746,8 +1073,7 @@ func detectUserShell() string {
 
 	// Fallback: try to get from /etc/passwd
 	if usr, err := user.Current(); err == nil {
-		cmd := exec.Command("getent", "passwd", usr.Username)
-		if output, err := cmd.Output(); err == nil {
+		if output, err := commandOutput("getent", "passwd", usr.Username); err == nil {
 			// Parse /etc/passwd format: username:x:uid:gid:comment:home:shell
 			fields := strings.Split(strings.TrimSpace(string(output)), ":")
 			if len(fields) >= 7 && fields[6] != "" {
@@ -759,3 +1085,14 @@ func detectUserShell() string {
 	// Final fallback
 	return "/bin/bash"
 }
+
+// runCommand runs a helper such as xdg-mime or lsregister to completion.
+// Helpers go through terminals.DefaultRunner so tests can record them.
+func runCommand(name string, args ...string) error {
+	return terminals.DefaultRunner.Run(terminals.Invocation{Name: name, Args: args, Wait: true})
+}
+
+// commandOutput runs a helper and returns its stdout
+func commandOutput(name string, args ...string) ([]byte, error) {
+	return terminals.DefaultRunner.Output(terminals.Invocation{Name: name, Args: args})
+}
@@ -1,23 +1,64 @@
 package main
 
 import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
 	"github.com/icanhazstring/sshlink/terminals"
 )
 
+// TestMain keeps tests away from the developer's config files and desktop
+func TestMain(m *testing.M) {
+	home, err := os.MkdirTemp("", "sshlink-test")
+	if err != nil {
+		panic(err)
+	}
+	os.Setenv("HOME", home)
+	os.Setenv("XDG_STATE_HOME", filepath.Join(home, "state"))
+	systemConfigDir = filepath.Join(home, "etc", "sshlink")
+	systemPrefixes = []string{filepath.Join(home, "usr", "local"), filepath.Join(home, "usr")}
+	notifier = &recordingNotifier{}
+
+	code := m.Run()
+	os.RemoveAll(home)
+	os.Exit(code)
+}
+
+// recordingNotifier captures notifications instead of showing them
+type recordingNotifier struct {
+	titles   []string
+	messages []string
+}
+
+func (n *recordingNotifier) Notify(title, message string) error {
+	n.titles = append(n.titles, title)
+	n.messages = append(n.messages, message)
+	return nil
+}
+
 // MockTerminal captures the commands passed to it
 type MockTerminal struct {
 	capturedCommand string
+	name            string // "" means MockTerminal
 }
 
-func (m *MockTerminal) Open(host string) error {
-	m.capturedCommand = host
+func (m *MockTerminal) Exec(command []string) error {
+	m.capturedCommand = strings.Join(command, " ")
 	return nil
 }
 
+func (m *MockTerminal) Plan(command []string) []terminals.Invocation {
+	return []terminals.Invocation{{Name: "mock-terminal", Args: command}}
+}
+
 func (m *MockTerminal) Name() string {
+	if m.name != "" {
+		return m.name
+	}
 	return "MockTerminal"
 }
 
@@ -25,6 +66,10 @@ func (m *MockTerminal) IsAvailable() bool {
 	return true
 }
 
+func (m *MockTerminal) SetTitle(title string) {}
+
+func (m *MockTerminal) SetProfile(profile string) {}
+
 func TestSSHLinkExecution(t *testing.T) {
 	tests := []struct {
 		name         string
@@ -34,22 +79,22 @@ func TestSSHLinkExecution(t *testing.T) {
 		{
 			name:         "Simple host",
 			url:          "sshlink://example.com",
-			expectedHost: "example.com",
+			expectedHost: "ssh example.com",
 		},
 		{
 			name:         "User and host",
 			url:          "sshlink://user@example.com",
-			expectedHost: "user@example.com",
+			expectedHost: "ssh user@example.com",
 		},
 		{
 			name:         "User, host and port",
 			url:          "sshlink://user@example.com:2222",
-			expectedHost: "user@example.com:2222",
+			expectedHost: "ssh -p 2222 user@example.com",
 		},
 		{
 			name:         "IPv6 address",
 			url:          "sshlink://user@[2001:db8::1]:22",
-			expectedHost: "user@[2001:db8::1]:22",
+			expectedHost: "ssh -p 22 user@2001:db8::1",
 		},
 	}
 
@@ -74,15 +119,159 @@ func TestSSHLinkExecution(t *testing.T) {
 
 			// Verify the mock captured the correct SSH command
 			if mock.capturedCommand != tt.expectedHost {
-				t.Errorf("Expected SSH command '%s', but terminal.Open() received '%s'",
+				t.Errorf("Expected SSH command '%s', but the terminal received '%s'",
 					tt.expectedHost, mock.capturedCommand)
 			}
 
-			t.Logf("✅ sshlink correctly passed '%s' to terminal.Open()", mock.capturedCommand)
+			t.Logf("✅ sshlink correctly passed '%s' to the terminal", mock.capturedCommand)
+		})
+	}
+}
+
+func TestAdditionalSchemes(t *testing.T) {
+	tests := []struct {
+		name            string
+		url             string
+		expectedCommand string
+	}{
+		{
+			name:            "SFTP with port",
+			url:             "sftplink://user@example.com:2222",
+			expectedCommand: "sftp -P 2222 user@example.com",
+		},
+		{
+			name:            "SFTP IPv6",
+			url:             "sftplink://[2001:db8::1]",
+			expectedCommand: "sftp [2001:db8::1]",
+		},
+		{
+			name:            "SFTP IPv6 with user",
+			url:             "sftplink://user@[2001:db8::1]:2222",
+			expectedCommand: "sftp -P 2222 user@[2001:db8::1]",
+		},
+		{
+			name:            "Mosh with ssh port",
+			url:             "moshlink://user@example.com:2222",
+			expectedCommand: `sh -c mosh '--ssh=ssh -p 2222' user@example.com || { echo 'sshlink: mosh failed, falling back to ssh'; exec ssh -p 2222 user@example.com; }`,
+		},
+		{
+			name:            "Eternal Terminal from link",
+			url:             "sshlink://user@example.com?transport=et",
+			expectedCommand: `sh -c et user@example.com || { echo 'sshlink: et failed, falling back to ssh'; exec ssh user@example.com; }`,
+		},
+		{
+			name:            "Kubernetes pod with container",
+			url:             "sshlink://k8s/prod-eu/payments/api-7d9f?container=app",
+			expectedCommand: "kubectl --context prod-eu --namespace payments exec -it api-7d9f -c app -- sh",
+		},
+		{
+			name:            "Docker container with shell",
+			url:             "sshlink://docker/web-1?shell=bash",
+			expectedCommand: "docker exec -it web-1 bash",
+		},
+		{
+			name:            "Port forward in terminal",
+			url:             "sshlink://admin@bastion:2222?forward=5432:db.internal:5432&forward=127.0.0.1:6379:cache:6379",
+			expectedCommand: "ssh -N -o ExitOnForwardFailure=yes -L 5432:db.internal:5432 -L 127.0.0.1:6379:cache:6379 -p 2222 admin@bastion",
+		},
+		{
+			name:            "Remote directory",
+			url:             "sshlink://app1?cd=/var/log/my%20app",
+			expectedCommand: `ssh -t app1 cd '/var/log/my app' && exec "${SHELL:-/bin/sh}" -l`,
+		},
+		{
+			name:            "Remote command in directory",
+			url:             "sshlink://deploy@app1:2222?cd=/var/log/app&cmd=tail%20-f%20app.log",
+			expectedCommand: "ssh -t -p 2222 deploy@app1 cd /var/log/app && tail -f app.log",
+		},
+		{
+			name:            "Copy helper",
+			url:             "scplink://user@example.com",
+			expectedCommand: "copy user@example.com",
+		},
+	}
+
+	writeTestFile(t, configPath(), "allow_remote_command=on\n")
+
+	// Pretend every transport client is installed locally
+	originalLookPath := lookPath
+	defer func() { lookPath = originalLookPath }()
+	lookPath = func(file string) (string, error) { return "/usr/bin/" + file, nil }
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &MockTerminal{}
+
+			originalTestCreateTerminal := terminals.TestCreateTerminal
+			defer func() { terminals.TestCreateTerminal = originalTestCreateTerminal }()
+
+			terminals.TestCreateTerminal = func(terminalType string) (terminals.Terminal, error) {
+				return mock, nil
+			}
+
+			if err := handleURL(tt.url, "terminal"); err != nil {
+				t.Fatalf("handleURL failed: %v", err)
+			}
+
+			// The copy helper re-runs this binary, whose path varies
+			if !strings.HasSuffix(mock.capturedCommand, tt.expectedCommand) {
+				t.Errorf("Expected command '%s', but terminal.Exec() received '%s'",
+					tt.expectedCommand, mock.capturedCommand)
+			}
 		})
 	}
 }
 
+func TestTransportFallback(t *testing.T) {
+	mock := &MockTerminal{}
+
+	originalTestCreateTerminal := terminals.TestCreateTerminal
+	defer func() { terminals.TestCreateTerminal = originalTestCreateTerminal }()
+	terminals.TestCreateTerminal = func(terminalType string) (terminals.Terminal, error) {
+		return mock, nil
+	}
+
+	originalLookPath := lookPath
+	defer func() { lookPath = originalLookPath }()
+	lookPath = func(file string) (string, error) { return "", errors.New("not found") }
+
+	if err := handleURL("moshlink://user@example.com:2222", "terminal"); err != nil {
+		t.Fatalf("handleURL failed: %v", err)
+	}
+
+	// Without a local mosh client the link opens a plain ssh session
+	if mock.capturedCommand != "ssh -p 2222 user@example.com" {
+		t.Errorf("Expected fallback to 'ssh -p 2222 user@example.com', got '%s'", mock.capturedCommand)
+	}
+}
+
+func TestFailureNotifications(t *testing.T) {
+	recorder := &recordingNotifier{}
+	originalNotifier := notifier
+	defer func() { notifier = originalNotifier }()
+	notifier = recorder
+
+	originalTestCreateTerminal := terminals.TestCreateTerminal
+	defer func() { terminals.TestCreateTerminal = originalTestCreateTerminal }()
+	terminals.TestCreateTerminal = func(terminalType string) (terminals.Terminal, error) {
+		if terminalType != "terminal" {
+			return nil, fmt.Errorf("unsupported terminal: %s", terminalType)
+		}
+		return &MockTerminal{}, nil
+	}
+
+	handleURL("sshlink://-oProxyCommand=calc", "terminal")
+	handleURL("sshlink://example.com", "xterm")
+	if err := handleURL("sshlink://example.com", "terminal"); err != nil {
+		t.Fatalf("handleURL failed: %v", err)
+	}
+
+	expected := []string{"sshlink: link rejected", "sshlink: unknown terminal"}
+	if strings.Join(recorder.titles, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected notifications %v, got %v", expected, recorder.titles)
+	}
+}
+
 func TestSSHLinkExecutionErrors(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -96,6 +285,66 @@ func TestSSHLinkExecutionErrors(t *testing.T) {
 			expectError: true,
 			errorMsg:    "no target specified",
 		},
+		{
+			name:        "Option injection in host",
+			url:         "sshlink://-oProxyCommand=calc",
+			expectError: true,
+			errorMsg:    "invalid host",
+		},
+		{
+			name:        "Incomplete Kubernetes path",
+			url:         "sshlink://k8s/prod-eu/payments",
+			expectError: true,
+			errorMsg:    "expected sshlink://k8s/<context>/<namespace>/<pod>",
+		},
+		{
+			name:        "Unsupported container shell",
+			url:         "sshlink://docker/web-1?shell=python",
+			expectError: true,
+			errorMsg:    "unsupported shell",
+		},
+		{
+			name:        "Invalid pod name",
+			url:         "sshlink://k8s/prod/default/-it",
+			expectError: true,
+			errorMsg:    "invalid pod",
+		},
+		{
+			name:        "Invalid forward",
+			url:         "sshlink://bastion?forward=5432:db.internal:5432%20-oProxyCommand=calc",
+			expectError: true,
+			errorMsg:    "invalid forward",
+		},
+		{
+			name:        "Forward bound to all interfaces",
+			url:         "sshlink://bastion?forward=0.0.0.0:5432:db.internal:5432",
+			expectError: true,
+			errorMsg:    "only localhost, 127.0.0.1 or [::1] are allowed",
+		},
+		{
+			name:        "Forward bound to a LAN address",
+			url:         "sshlink://bastion?forward=192.168.1.10:5432:db.internal:5432",
+			expectError: true,
+			errorMsg:    "binds to 192.168.1.10",
+		},
+		{
+			name:        "Control characters in command",
+			url:         "sshlink://app1?cmd=uptime%0Areboot",
+			expectError: true,
+			errorMsg:    "control characters",
+		},
+		{
+			name:        "Unknown transport",
+			url:         "sshlink://example.com?transport=telnet",
+			expectError: true,
+			errorMsg:    "unsupported transport",
+		},
+		{
+			name:        "Unknown scheme",
+			url:         "telnetlink://example.com",
+			expectError: true,
+			errorMsg:    "unsupported scheme",
+		},
 	}
 
 	for _, tt := range tests {
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"github.com/icanhazstring/sshlink/logging"
+	"github.com/icanhazstring/sshlink/terminals"
+)
+
+// nativeHostName is the native messaging host name extensions connect to
+const nativeHostName = "com.icanhazstring.sshlink"
+
+// maxNativeMessageSize is Chrome's limit for messages sent to a native host
+const maxNativeMessageSize = 1024 * 1024
+
+type nativeRequest struct {
+	URL string `json:"url"`
+}
+
+func runNativeHost(args []string) error {
+	fs := flag.NewFlagSet("native-host", flag.ExitOnError)
+	install := fs.Bool("install", false, "Write native messaging host manifests for installed browsers")
+	uninstall := fs.Bool("uninstall", false, "Remove native messaging host manifests")
+	chromeID := fs.String("chrome-extension", "", "Chrome/Chromium extension ID allowed to connect")
+	firefoxID := fs.String("firefox-extension", "", "Firefox extension ID allowed to connect")
+	terminal := fs.String("terminal", "terminal", "Terminal to use")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: sshlink native-host [options]\n\nOptions:\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	switch {
+	case *install:
+		return installNativeHost(*chromeID, *firefoxID)
+	case *uninstall:
+		return uninstallNativeHost()
+	default:
+		// Launched by the browser: the remaining arguments are the caller's
+		// origin (Chrome) or manifest path and extension ID (Firefox)
+		logging.Debugf("native-host: started by %v", fs.Args())
+
+		// stdout belongs to the messaging protocol, so send the usual
+		// progress output to stderr, which browsers log
+		messages := os.Stdout
+		os.Stdout = os.Stderr
+		return serveNativeMessages(os.Stdin, messages, resolveTerminalType(*terminal), handleURL)
+	}
+}
+
+// serveNativeMessages answers length-prefixed JSON requests until the
+// browser closes stdin, replying with the same response shape as serve
+func serveNativeMessages(r io.Reader, w io.Writer, terminalType string, open func(urlString, terminalType string) error) error {
+	for {
+		var req nativeRequest
+		err := readNativeMessage(r, &req)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		resp := openResponse{OK: true, Target: req.URL}
+		if req.URL == "" {
+			resp = openResponse{Error: "no url specified"}
+		} else if err := open(req.URL, terminalType); err != nil {
+			resp = openResponse{Target: req.URL, Error: err.Error()}
+		}
+
+		if err := writeNativeMessage(w, resp); err != nil {
+			return err
+		}
+	}
+}
+
+// readNativeMessage decodes one message: a 32-bit native-endian length
+// followed by that many bytes of UTF-8 JSON
+func readNativeMessage(r io.Reader, v any) error {
+	var length uint32
+	if err := binary.Read(r, binary.NativeEndian, &length); err != nil {
+		return err
+	}
+	if length > maxNativeMessageSize {
+		return fmt.Errorf("native message too large: %d bytes", length)
+	}
+
+	buf := make([]byte, length)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return fmt.Errorf("failed to read native message: %v", err)
+	}
+	return json.Unmarshal(buf, v)
+}
+
+func writeNativeMessage(w io.Writer, v any) error {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if err := binary.Write(w, binary.NativeEndian, uint32(len(payload))); err != nil {
+		return err
+	}
+	_, err = w.Write(payload)
+	return err
+}
+
+// nativeHostDirs returns the per-user NativeMessagingHosts directories of
+// Chromium-based browsers and Firefox, which use different manifest keys
+func nativeHostDirs() (chrome []string, firefox []string, err error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get home directory: %v", err)
+	}
+
+	switch runtime.GOOS {
+	case "darwin":
+		support := filepath.Join(homeDir, "Library", "Application Support")
+		chrome = []string{
+			filepath.Join(support, "Google", "Chrome", "NativeMessagingHosts"),
+			filepath.Join(support, "Chromium", "NativeMessagingHosts"),
+			filepath.Join(support, "Microsoft Edge", "NativeMessagingHosts"),
+			filepath.Join(support, "BraveSoftware", "Brave-Browser", "NativeMessagingHosts"),
+		}
+		firefox = []string{filepath.Join(support, "Mozilla", "NativeMessagingHosts")}
+	case "linux":
+		config := filepath.Join(homeDir, ".config")
+		chrome = []string{
+			filepath.Join(config, "google-chrome", "NativeMessagingHosts"),
+			filepath.Join(config, "chromium", "NativeMessagingHosts"),
+			filepath.Join(config, "microsoft-edge", "NativeMessagingHosts"),
+			filepath.Join(config, "BraveSoftware", "Brave-Browser", "NativeMessagingHosts"),
+		}
+		firefox = []string{filepath.Join(homeDir, ".mozilla", "native-messaging-hosts")}
+	default:
+		return nil, nil, fmt.Errorf("native messaging install not supported on %s", runtime.GOOS)
+	}
+	return chrome, firefox, nil
+}
+
+func installNativeHost(chromeID, firefoxID string) error {
+	if chromeID == "" && firefoxID == "" {
+		return fmt.Errorf("pass -chrome-extension and/or -firefox-extension with the companion extension ID")
+	}
+
+	execPath, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to get executable path: %v", err)
+	}
+
+	chromeDirs, firefoxDirs, err := nativeHostDirs()
+	if err != nil {
+		return err
+	}
+
+	// Browsers start the manifest's path without extra arguments, so point
+	// it at a small launcher script that adds the native-host subcommand
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return fmt.Errorf("failed to get home directory: %v", err)
+	}
+	launcherPath := filepath.Join(homeDir, ".config", "sshlink", "native-host")
+	if err := os.MkdirAll(filepath.Dir(launcherPath), 0755); err != nil {
+		return fmt.Errorf("failed to create config directory: %v", err)
+	}
+	launcher := fmt.Sprintf("#!/bin/sh\nexec %s native-host \"$@\"\n", terminals.ShellQuote(execPath))
+	if err := os.WriteFile(launcherPath, []byte(launcher), 0755); err != nil {
+		return fmt.Errorf("failed to write native host launcher: %v", err)
+	}
+	fmt.Printf("📄 Created native host launcher: %s\n", launcherPath)
+
+	manifest := map[string]any{
+		"name":        nativeHostName,
+		"description": "sshlink - open SSH sessions from the browser",
+		"path":        launcherPath,
+		"type":        "stdio",
+	}
+
+	if chromeID != "" {
+		manifest["allowed_origins"] = []string{fmt.Sprintf("chrome-extension://%s/", chromeID)}
+		if err := writeNativeManifests(chromeDirs, manifest); err != nil {
+			return err
+		}
+		delete(manifest, "allowed_origins")
+	}
+
+	if firefoxID != "" {
+		manifest["allowed_extensions"] = []string{firefoxID}
+		if err := writeNativeManifests(firefoxDirs, manifest); err != nil {
+			return err
+		}
+	}
+
+	fmt.Println("✅ Native messaging host installed")
+	fmt.Println("   Restart your browser so the extension can connect")
+	return nil
+}
+
+func writeNativeManifests(dirs []string, manifest map[string]any) error {
+	content, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode manifest: %v", err)
+	}
+
+	for _, dir := range dirs {
+		// Only register with browsers that are installed for this user
+		if _, err := os.Stat(filepath.Dir(dir)); err != nil {
+			continue
+		}
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create %s: %v", dir, err)
+		}
+		manifestPath := filepath.Join(dir, nativeHostName+".json")
+		if err := os.WriteFile(manifestPath, append(content, '\n'), 0644); err != nil {
+			return fmt.Errorf("failed to write manifest: %v", err)
+		}
+		fmt.Printf("📄 Created manifest: %s\n", manifestPath)
+	}
+	return nil
+}
+
+func uninstallNativeHost() error {
+	chromeDirs, firefoxDirs, err := nativeHostDirs()
+	if err != nil {
+		return err
+	}
+
+	for _, dir := range append(chromeDirs, firefoxDirs...) {
+		manifestPath := filepath.Join(dir, nativeHostName+".json")
+		if _, err := os.Stat(manifestPath); err == nil {
+			if err := os.Remove(manifestPath); err != nil {
+				return fmt.Errorf("failed to remove manifest: %v", err)
+			}
+			fmt.Printf("🗑️  Removed manifest: %s\n", manifestPath)
+		}
+	}
+
+	fmt.Println("✅ Native messaging host uninstalled")
+	return nil
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestServeNativeMessages(t *testing.T) {
+	var input bytes.Buffer
+	for _, msg := range []nativeRequest{
+		{URL: "sshlink://user@example.com"},
+		{URL: "sshlink://fail.example.com"},
+		{},
+	} {
+		if err := writeNativeMessage(&input, msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var opened []string
+	open := func(urlString, terminalType string) error {
+		if urlString == "sshlink://fail.example.com" {
+			return errors.New("launch failed")
+		}
+		opened = append(opened, urlString)
+		return nil
+	}
+
+	var output bytes.Buffer
+	if err := serveNativeMessages(&input, &output, "terminal", open); err != nil {
+		t.Fatalf("serveNativeMessages failed: %v", err)
+	}
+
+	want := []openResponse{
+		{OK: true, Target: "sshlink://user@example.com"},
+		{Target: "sshlink://fail.example.com", Error: "launch failed"},
+		{Error: "no url specified"},
+	}
+	for i, w := range want {
+		var got openResponse
+		if err := readNativeMessage(&output, &got); err != nil {
+			t.Fatalf("reply %d: %v", i, err)
+		}
+		if got != w {
+			t.Errorf("reply %d: expected %+v, got %+v", i, w, got)
+		}
+	}
+	if err := readNativeMessage(&output, &openResponse{}); err != io.EOF {
+		t.Errorf("expected exactly %d replies, got extra data (%v)", len(want), err)
+	}
+	if len(opened) != 1 || opened[0] != "sshlink://user@example.com" {
+		t.Errorf("unexpected opened URLs: %v", opened)
+	}
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"github.com/icanhazstring/sshlink/logging"
+	"github.com/icanhazstring/sshlink/notify"
+)
+
+// notifier reports failures to the user; tests replace it with a fake
+var notifier = newNotifier()
+
+// newNotifier honours notify=off in the config
+func newNotifier() notify.Notifier {
+	if value := readConfigValue("notify"); value == "off" || value == "false" {
+		return notify.Nop{}
+	}
+	return notify.New()
+}
+
+// notifyError shows err as a desktop notification and returns it, since
+// errors in URL-handler mode otherwise only reach the log file
+func notifyError(title string, err error) error {
+	if nerr := notifier.Notify(title, err.Error()); nerr != nil {
+		logging.Warnf("Failed to show notification: %v", nerr)
+	}
+	return err
+}
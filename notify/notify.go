@@ -0,0 +1,57 @@
+package notify
+
+import (
+	"fmt"
+	"os/exec"
+	"runtime"
+
+	"github.com/icanhazstring/sshlink/terminals"
+)
+
+// Notifier shows desktop notifications to the user who clicked a link
+type Notifier interface {
+	Notify(title, message string) error
+}
+
+// New returns the notifier for the current platform, or Nop if it has none
+func New() Notifier {
+	switch runtime.GOOS {
+	case "darwin":
+		return MacOSNotifier{}
+	case "linux":
+		return LinuxNotifier{}
+	default:
+		return Nop{}
+	}
+}
+
+// Nop discards notifications, used when notifications are turned off
+type Nop struct{}
+
+func (Nop) Notify(title, message string) error {
+	return nil
+}
+
+// MacOSNotifier uses AppleScript's display notification
+type MacOSNotifier struct{}
+
+func (MacOSNotifier) Notify(title, message string) error {
+	script := fmt.Sprintf("display notification %s with title %s", terminals.AppleScriptString(message), terminals.AppleScriptString(title))
+	return exec.Command("osascript", "-e", script).Run()
+}
+
+// LinuxNotifier uses notify-send, falling back to calling the
+// org.freedesktop.Notifications D-Bus service through gdbus
+type LinuxNotifier struct{}
+
+func (LinuxNotifier) Notify(title, message string) error {
+	if _, err := exec.LookPath("notify-send"); err == nil {
+		return exec.Command("notify-send", "--app-name=sshlink", title, message).Run()
+	}
+
+	return exec.Command("gdbus", "call", "--session",
+		"--dest", "org.freedesktop.Notifications",
+		"--object-path", "/org/freedesktop/Notifications",
+		"--method", "org.freedesktop.Notifications.Notify",
+		"sshlink", "0", "utilities-terminal", title, message, "[]", "{}", "5000").Run()
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"embed"
+	"flag"
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/icanhazstring/sshlink/inventory"
+	"github.com/icanhazstring/sshlink/terminals"
+)
+
+//go:embed templates/launcher.html
+var launcherPageFS embed.FS
+
+// hostGroup is one tag section of the launcher page
+type hostGroup struct {
+	Tag   string
+	Hosts []inventory.Host
+}
+
+func runPage(args []string) error {
+	var source hostSource
+	fs := flag.NewFlagSet("page", flag.ExitOnError)
+	source.register(fs)
+	title := fs.String("title", "SSH Launcher", "Page title")
+	output := fs.String("o", "sshlink.html", "Output file, - for stdout")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: sshlink page [options]\n\nOptions:\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	hosts, err := source.load()
+	if err != nil {
+		return err
+	}
+
+	var w io.Writer = os.Stdout
+	if *output != "-" {
+		f, err := os.Create(*output)
+		if err != nil {
+			return fmt.Errorf("failed to create output file: %v", err)
+		}
+		defer f.Close()
+		w = f
+	}
+
+	if err := writeLauncherPage(w, *title, hosts); err != nil {
+		return err
+	}
+
+	if *output != "-" {
+		fmt.Printf("📄 Wrote launcher page with %d hosts to %s\n", len(hosts), *output)
+	}
+	return nil
+}
+
+func writeLauncherPage(w io.Writer, title string, hosts []inventory.Host) error {
+	content, err := launcherPageFS.ReadFile("templates/launcher.html")
+	if err != nil {
+		return fmt.Errorf("failed to read embedded launcher template: %v", err)
+	}
+
+	funcs := template.FuncMap{
+		"join":       strings.Join,
+		"sshCommand": sshCommand,
+	}
+	for name, fn := range linkFuncs {
+		funcs[name] = fn
+	}
+
+	tmpl, err := template.New("launcher").Funcs(funcs).Parse(string(content))
+	if err != nil {
+		return fmt.Errorf("failed to parse launcher template: %v", err)
+	}
+
+	return tmpl.Execute(w, struct {
+		Title   string
+		Version string
+		Hosts   []inventory.Host
+		Groups  []hostGroup
+	}{
+		Title:   title,
+		Version: version,
+		Hosts:   hosts,
+		Groups:  groupByTag(hosts),
+	})
+}
+
+// groupByTag sorts hosts into one group per tag; a host with several tags
+// appears in each of them and untagged hosts are collected last
+func groupByTag(hosts []inventory.Host) []hostGroup {
+	index := map[string]int{}
+	var groups []hostGroup
+	var untagged []inventory.Host
+	for _, h := range hosts {
+		if len(h.Tags) == 0 {
+			untagged = append(untagged, h)
+			continue
+		}
+		for _, tag := range h.Tags {
+			i, ok := index[tag]
+			if !ok {
+				i = len(groups)
+				index[tag] = i
+				groups = append(groups, hostGroup{Tag: tag})
+			}
+			groups[i].Hosts = append(groups[i].Hosts, h)
+		}
+	}
+
+	sort.SliceStable(groups, func(i, j int) bool { return groups[i].Tag < groups[j].Tag })
+	if len(untagged) > 0 {
+		groups = append(groups, hostGroup{Tag: "untagged", Hosts: untagged})
+	}
+	return groups
+}
+
+// sshCommand is the plain ssh command line for a host, offered as a
+// copy-to-clipboard fallback when the sshlink:// handler isn't installed.
+// Inventory values are quoted, so a pasted command can't run anything else.
+func sshCommand(h inventory.Host) string {
+	argv := []string{"ssh"}
+	if h.Port != "" {
+		argv = append(argv, "-p", h.Port)
+	}
+	destination := h.Host
+	if h.User != "" {
+		destination = h.User + "@" + h.Host
+	}
+	if strings.HasPrefix(destination, "-") {
+		argv = append(argv, "--") // not an option
+	}
+	return terminals.ShellJoin(append(argv, destination))
+}
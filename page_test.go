@@ -0,0 +1,63 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/icanhazstring/sshlink/inventory"
+)
+
+func TestSSHCommand(t *testing.T) {
+	tests := []struct {
+		host     inventory.Host
+		expected string
+	}{
+		{inventory.Host{Host: "app1"}, "ssh app1"},
+		{inventory.Host{Host: "app1", User: "deploy", Port: "2222"}, "ssh -p 2222 deploy@app1"},
+		{inventory.Host{Host: "app1;reboot", User: "$(id)"}, `ssh '$(id)@app1;reboot'`},
+		{inventory.Host{Host: "-oProxyCommand=calc"}, "ssh -- -oProxyCommand=calc"},
+		{inventory.Host{Host: "app1", Port: "22 && rm -rf ~"}, `ssh -p '22 && rm -rf ~' app1`},
+	}
+	for _, tt := range tests {
+		if got := sshCommand(tt.host); got != tt.expected {
+			t.Errorf("sshCommand(%+v) = %s, expected %s", tt.host, got, tt.expected)
+		}
+	}
+}
+
+func TestWriteLauncherPage(t *testing.T) {
+	hosts := []inventory.Host{
+		{Name: "web", Host: "web1", Tags: []string{"prod", "eu"}},
+		{Name: `<script>alert(1)</script>`, Host: "app1`reboot`", User: "deploy"},
+	}
+
+	var out bytes.Buffer
+	if err := writeLauncherPage(&out, `Hosts & "more"`, hosts); err != nil {
+		t.Fatalf("writeLauncherPage failed: %v", err)
+	}
+	page := out.String()
+
+	for _, expected := range []string{
+		"<title>Hosts &amp; &#34;more&#34;</title>",
+		`href="sshlink://web1"`,
+		"&lt;script&gt;alert(1)&lt;/script&gt;",
+		`data-command="ssh &#39;deploy@app1`,
+	} {
+		if !strings.Contains(page, expected) {
+			t.Errorf("Expected %s in the page", expected)
+		}
+	}
+	if strings.Contains(page, "<script>alert(1)") {
+		t.Errorf("Expected the host name to be escaped")
+	}
+
+	groups := groupByTag(hosts)
+	var tags []string
+	for _, g := range groups {
+		tags = append(tags, g.Tag)
+	}
+	if strings.Join(tags, ",") != "eu,prod,untagged" {
+		t.Errorf("Expected groups eu, prod, untagged, got %v", tags)
+	}
+}
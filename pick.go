@@ -0,0 +1,78 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/icanhazstring/sshlink/inventory"
+	"github.com/icanhazstring/sshlink/picker"
+	"github.com/icanhazstring/sshlink/sshconfig"
+)
+
+func runPick(args []string) error {
+	fs := flag.NewFlagSet("pick", flag.ExitOnError)
+	terminal := fs.String("terminal", "terminal", "Terminal to use")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: sshlink pick [options]\n\nType to filter, arrows or Ctrl-N/Ctrl-P to move, Enter to open, Esc to cancel.\n\nOptions:\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	items := pickItems()
+	if len(items) == 0 {
+		return fmt.Errorf("nothing to pick from: no history, favourites or ~/.ssh/config hosts")
+	}
+
+	restore, err := picker.MakeRaw(os.Stdin)
+	if err != nil {
+		return fmt.Errorf("pick needs an interactive terminal: %v", err)
+	}
+	item, err := picker.New(os.Stdin, os.Stdout, items).Run()
+	restore()
+	if err == picker.ErrCancelled {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	return handleURL(item.Value, resolveTerminalType(*terminal))
+}
+
+// pickItems lists recent hosts first, then favourites, then ssh_config
+// aliases, skipping links that were already listed
+func pickItems() []picker.Item {
+	var items []picker.Item
+	seen := map[string]bool{}
+	add := func(item picker.Item) {
+		if !seen[item.Value] {
+			seen[item.Value] = true
+			items = append(items, item)
+		}
+	}
+
+	if entries, err := recentHistory(); err == nil {
+		for _, e := range entries {
+			add(picker.Item{Label: strings.TrimPrefix(e.Link, "sshlink://"), Detail: "recent", Value: e.Link})
+		}
+	}
+
+	if favs, err := loadFavourites(); err == nil {
+		for _, f := range favs {
+			detail := "favourite"
+			if len(f.Tags) > 0 {
+				detail += " " + strings.Join(f.Tags, " ")
+			}
+			add(picker.Item{Label: f.Name, Detail: detail, Value: f.URL})
+		}
+	}
+
+	if cfg, err := sshconfig.Load(sshconfig.DefaultPath()); err == nil {
+		for _, h := range inventory.FromSSHConfig(cfg, false) {
+			add(picker.Item{Label: h.Name, Detail: "ssh_config", Value: h.Link()})
+		}
+	}
+	return items
+}
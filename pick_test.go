@@ -0,0 +1,40 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPickItems(t *testing.T) {
+	home, _ := os.UserHomeDir()
+	sshDir := filepath.Join(home, ".ssh")
+	os.MkdirAll(sshDir, 0700)
+	os.WriteFile(filepath.Join(sshDir, "config"), []byte("Host bastion app1\n  User deploy\n"), 0600)
+	defer os.RemoveAll(sshDir)
+	defer os.Remove(favouritesPath())
+	defer os.Remove(historyPath())
+	os.Remove(historyPath())
+
+	if err := addHistory(session{link: "sshlink://app1", target: Target{Host: "app1"}}, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if err := runFav([]string{"add", "sshlink://bastion", "-name", "Jump host", "-tag", "prod"}); err != nil {
+		t.Fatal(err)
+	}
+
+	items := pickItems()
+	expected := []struct{ label, detail string }{
+		{"app1", "recent"},
+		{"Jump host", "favourite prod"},
+	}
+	if len(items) != len(expected) {
+		t.Fatalf("Expected %d items, got %+v", len(expected), items)
+	}
+	for i, e := range expected {
+		if items[i].Label != e.label || items[i].Detail != e.detail {
+			t.Errorf("Item %d: expected %s (%s), got %s (%s)", i, e.label, e.detail, items[i].Label, items[i].Detail)
+		}
+	}
+}
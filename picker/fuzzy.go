@@ -0,0 +1,75 @@
+package picker
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Score reports whether every rune of query appears in text in order,
+// ignoring case, and how well it matches. Consecutive runes and runes at
+// the start of a word score higher, gaps score lower.
+func Score(query, text string) (int, bool) {
+	if query == "" {
+		return 0, true
+	}
+	q := []rune(strings.ToLower(query))
+	t := []rune(strings.ToLower(text))
+
+	// Matching greedily from the first occurrence misses better matches
+	// later on, e.g. "db" in "prod-db", so try every starting point
+	best, found := 0, false
+	for start := range t {
+		if t[start] != q[0] {
+			continue
+		}
+		if score, ok := scoreFrom(q, t, start); ok && (!found || score > best) {
+			best, found = score, true
+		}
+	}
+	return best, found
+}
+
+func scoreFrom(q, t []rune, start int) (int, bool) {
+	score, qi, last := 0, 0, -1
+	for ti := start; ti < len(t) && qi < len(q); ti++ {
+		if t[ti] != q[qi] {
+			continue
+		}
+		score++
+		switch {
+		case ti == last+1 && last >= 0:
+			score += 5
+		case ti == 0 || !unicode.IsLetter(t[ti-1]) && !unicode.IsDigit(t[ti-1]):
+			score += 3
+		}
+		if last >= 0 {
+			score -= min(ti-last-1, 3)
+		}
+		last = ti
+		qi++
+	}
+	return score, qi == len(q)
+}
+
+// Filter returns the items matching query, best matches first. Items with
+// equal scores keep their original order.
+func Filter(items []Item, query string) []Item {
+	type scored struct {
+		item  Item
+		score int
+	}
+	var matches []scored
+	for _, item := range items {
+		if score, ok := Score(query, item.Label+" "+item.Detail); ok {
+			matches = append(matches, scored{item, score})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].score > matches[j].score })
+
+	filtered := make([]Item, len(matches))
+	for i, m := range matches {
+		filtered[i] = m.item
+	}
+	return filtered
+}
@@ -0,0 +1,185 @@
+package picker
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ErrCancelled is returned when the user leaves the picker without choosing
+var ErrCancelled = errors.New("cancelled")
+
+// Item is one choice in the picker
+type Item struct {
+	Label  string // shown and searched
+	Detail string // shown dimmed after the label, also searched
+	Value  string // returned to the caller, e.g. a link
+}
+
+// Picker is a fuzzy-searchable list driven by keystrokes read from in and
+// drawn with ANSI escapes to out. It doesn't touch the terminal mode itself,
+// see MakeRaw.
+type Picker struct {
+	Items  []Item
+	Prompt string
+	Height int // maximum number of items shown
+
+	in       *bufio.Reader
+	out      io.Writer
+	query    []rune
+	matches  []Item
+	selected int
+}
+
+// New creates a picker over items reading keys from in and drawing to out
+func New(in io.Reader, out io.Writer, items []Item) *Picker {
+	return &Picker{
+		Items:  items,
+		Prompt: "> ",
+		Height: 15,
+		in:     bufio.NewReader(in),
+		out:    out,
+	}
+}
+
+// Run shows the picker until an item is chosen with Enter or the user
+// cancels with Esc, Ctrl-C or Ctrl-D
+func (p *Picker) Run() (Item, error) {
+	// Draw on the alternate screen so the picker leaves no trace
+	fmt.Fprint(p.out, "\033[?1049h")
+	defer fmt.Fprint(p.out, "\033[?1049l")
+
+	p.update()
+	for {
+		p.draw()
+
+		r, _, err := p.in.ReadRune()
+		if err == io.EOF {
+			return Item{}, ErrCancelled
+		}
+		if err != nil {
+			return Item{}, err
+		}
+
+		switch r {
+		case '\r', '\n':
+			if len(p.matches) == 0 {
+				continue
+			}
+			return p.matches[p.selected], nil
+		case 3, 4: // Ctrl-C, Ctrl-D
+			return Item{}, ErrCancelled
+		case 27: // Esc, or the start of an arrow key sequence
+			if !p.readEscape() {
+				return Item{}, ErrCancelled
+			}
+		case 16: // Ctrl-P
+			p.move(-1)
+		case 14: // Ctrl-N
+			p.move(1)
+		case 127, 8: // Backspace
+			if len(p.query) > 0 {
+				p.query = p.query[:len(p.query)-1]
+				p.update()
+			}
+		case 21: // Ctrl-U
+			p.query = nil
+			p.update()
+		default:
+			if unicode.IsPrint(r) {
+				p.query = append(p.query, r)
+				p.update()
+			}
+		}
+	}
+}
+
+// readEscape handles the rest of an escape sequence, returning false for a
+// lone Esc. Terminals send arrow keys as a whole, so a sequence is only
+// assumed when more input is already buffered.
+func (p *Picker) readEscape() bool {
+	if p.in.Buffered() == 0 {
+		return false
+	}
+	next, _ := p.in.ReadByte()
+	if next != '[' && next != 'O' {
+		return false
+	}
+	key, _ := p.in.ReadByte()
+	switch key {
+	case 'A':
+		p.move(-1)
+	case 'B':
+		p.move(1)
+	}
+	return true
+}
+
+func (p *Picker) move(delta int) {
+	if len(p.matches) == 0 {
+		return
+	}
+	p.selected = (p.selected + delta + len(p.matches)) % len(p.matches)
+}
+
+func (p *Picker) update() {
+	p.matches = Filter(p.Items, string(p.query))
+	p.selected = 0
+}
+
+func (p *Picker) draw() {
+	var b strings.Builder
+	// Home the cursor and clear, lines end in \r\n as output isn't
+	// post-processed in raw mode
+	b.WriteString("\033[H\033[J")
+	fmt.Fprintf(&b, "%s%s\r\n", p.Prompt, string(p.query))
+	fmt.Fprintf(&b, "\033[2m  %d/%d\033[0m\r\n", len(p.matches), len(p.Items))
+
+	// Scroll so the selection stays visible
+	start := 0
+	if p.selected >= p.Height {
+		start = p.selected - p.Height + 1
+	}
+	for i := start; i < len(p.matches) && i < start+p.Height; i++ {
+		item := p.matches[i]
+		line := item.Label
+		if item.Detail != "" {
+			line += "  \033[2m" + item.Detail + "\033[22m"
+		}
+		if i == p.selected {
+			fmt.Fprintf(&b, "\033[7m> %s\033[0m\r\n", line)
+		} else {
+			fmt.Fprintf(&b, "  %s\r\n", line)
+		}
+	}
+
+	// Put the cursor back at the end of the query
+	fmt.Fprintf(&b, "\033[1;%dH", utf8.RuneCountInString(p.Prompt)+len(p.query)+1)
+	io.WriteString(p.out, b.String())
+}
+
+// MakeRaw puts the terminal on f into raw mode using stty, returning a
+// function that restores the previous mode
+func MakeRaw(f *os.File) (func(), error) {
+	stty := func(args ...string) (string, error) {
+		cmd := exec.Command("stty", args...)
+		cmd.Stdin = f
+		out, err := cmd.Output()
+		return strings.TrimSpace(string(out)), err
+	}
+
+	saved, err := stty("-g")
+	if err != nil {
+		return nil, fmt.Errorf("not an interactive terminal")
+	}
+	if _, err := stty("raw", "-echo"); err != nil {
+		return nil, fmt.Errorf("failed to set raw mode: %v", err)
+	}
+	return func() { stty(saved) }, nil
+}
@@ -0,0 +1,94 @@
+package picker
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+var testItems = []Item{
+	{Label: "prod-db-1", Detail: "recent", Value: "sshlink://prod-db-1"},
+	{Label: "staging-web", Detail: "ssh_config", Value: "sshlink://staging-web"},
+	{Label: "Prod DB replica", Detail: "favourite prod", Value: "sshlink://deploy@10.0.0.2"},
+	{Label: "bastion", Detail: "ssh_config", Value: "sshlink://bastion"},
+}
+
+func TestScore(t *testing.T) {
+	if _, ok := Score("pdb", "prod-db-1"); !ok {
+		t.Errorf("Expected pdb to match prod-db-1")
+	}
+	if _, ok := Score("xyz", "prod-db-1"); ok {
+		t.Errorf("Expected xyz not to match prod-db-1")
+	}
+
+	consecutive, _ := Score("db", "prod-db-1")
+	scattered, _ := Score("db", "deploy-b")
+	if consecutive <= scattered {
+		t.Errorf("Expected consecutive match to score higher: %d <= %d", consecutive, scattered)
+	}
+}
+
+func TestFilter(t *testing.T) {
+	matches := Filter(testItems, "prod")
+	if len(matches) != 2 {
+		t.Fatalf("Expected 2 matches for prod, got %v", matches)
+	}
+	if len(Filter(testItems, "")) != len(testItems) {
+		t.Errorf("Expected an empty query to match every item")
+	}
+}
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+		err      error
+	}{
+		{name: "Enter picks first", input: "\r", expected: "sshlink://prod-db-1"},
+		{name: "Query narrows", input: "bast\r", expected: "sshlink://bastion"},
+		{name: "Arrow down", input: "\033[B\r", expected: "sshlink://staging-web"},
+		{name: "Arrow up wraps", input: "\033[A\r", expected: "sshlink://bastion"},
+		{name: "Ctrl-N and Ctrl-P", input: "\x0e\x0e\x10\r", expected: "sshlink://staging-web"},
+		{name: "Backspace", input: "bastx\x7f\r", expected: "sshlink://bastion"},
+		{name: "Ctrl-U clears query", input: "zzz\x15\r", expected: "sshlink://prod-db-1"},
+		{name: "Enter without matches is ignored", input: "zzz\r\x7f\x7f\x7fstag\r", expected: "sshlink://staging-web"},
+		{name: "Ctrl-C cancels", input: "prod\x03", err: ErrCancelled},
+		{name: "Lone Esc cancels", input: "\033", err: ErrCancelled},
+		{name: "End of input cancels", input: "prod", err: ErrCancelled},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			item, err := New(strings.NewReader(tt.input), &out, testItems).Run()
+			if err != tt.err {
+				t.Fatalf("Expected error %v, got %v", tt.err, err)
+			}
+			if item.Value != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, item.Value)
+			}
+			if !strings.HasSuffix(out.String(), "\033[?1049l") {
+				t.Errorf("Expected the alternate screen to be left")
+			}
+		})
+	}
+}
+
+func TestRunDraw(t *testing.T) {
+	var out bytes.Buffer
+	New(strings.NewReader("stag\r"), &out, testItems).Run()
+
+	// The last frame shows the query and the highlighted match
+	frames := strings.Split(out.String(), "\033[H\033[J")
+	last := frames[len(frames)-1]
+	if !strings.Contains(last, "> stag\r\n") {
+		t.Errorf("Expected the query in the last frame, got %q", last)
+	}
+	if !strings.Contains(last, "\033[7m> staging-web") {
+		t.Errorf("Expected staging-web to be highlighted, got %q", last)
+	}
+	if strings.Contains(last, "bastion") {
+		t.Errorf("Expected bastion to be filtered out, got %q", last)
+	}
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// policy restricts which links sshlink opens. It is read from the system
+// policy file and the user's config; both can only add restrictions, so
+// a user can't loosen what the administrator set. The exception is the
+// opt-in for cmd= links.
+type policy struct {
+	denyHosts []string
+	// allowHosts holds one allowlist per file that sets allow_host,
+	// a host has to match every one of them
+	allowHosts      [][]string
+	disabledSchemes []string
+	// cmd= runs anything on the remote host, so it needs an explicit
+	// allow_remote_command=on. Switching it off, in any file, also blocks
+	// cd= and can't be switched back on.
+	remoteCommandOptIn bool
+	remoteCommandOff   bool
+	allowForward       bool
+}
+
+func systemPolicyPath() string {
+	return filepath.Join(systemConfigDir, "policy")
+}
+
+// loadPolicy reads the system policy, then the policy keys of the user's
+// config. Any error, in either file, means the policy can't be trusted and
+// links are refused: a mistyped deny_host must not quietly allow a host.
+func loadPolicy() (*policy, error) {
+	p := &policy{allowForward: true}
+	for _, policyFile := range []string{systemPolicyPath(), configPath()} {
+		if policyFile == "" {
+			continue
+		}
+		entries, err := parseConfigFile(policyFile)
+		if err != nil {
+			return nil, err
+		}
+		if err := p.apply(policyFile, entries); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+func (p *policy) apply(policyFile string, entries []configEntry) error {
+	var allow []string
+	for _, entry := range entries {
+		switch entry.key {
+		case "deny_host", "allow_host":
+			if _, err := path.Match(entry.value, ""); err != nil {
+				return fmt.Errorf("%s line %d: invalid host pattern %q", policyFile, entry.line, entry.value)
+			}
+			if entry.key == "deny_host" {
+				p.denyHosts = append(p.denyHosts, entry.value)
+			} else {
+				allow = append(allow, entry.value)
+			}
+		case "disable_scheme":
+			p.disabledSchemes = append(p.disabledSchemes, entry.value)
+		case "allow_remote_command", "allow_forward":
+			enabled, err := parseSwitch(entry.value)
+			if err != nil {
+				return fmt.Errorf("%s line %d: %s: %v", policyFile, entry.line, entry.key, err)
+			}
+			if entry.key == "allow_remote_command" {
+				p.remoteCommandOptIn = p.remoteCommandOptIn || enabled
+				p.remoteCommandOff = p.remoteCommandOff || !enabled
+			}
+			// Only ever switch off, never back on
+			if !enabled && entry.key == "allow_forward" {
+				p.allowForward = false
+			}
+		}
+	}
+	if len(allow) > 0 {
+		p.allowHosts = append(p.allowHosts, allow)
+	}
+	return nil
+}
+
+func parseSwitch(value string) (bool, error) {
+	switch value {
+	case "on", "true", "1", "yes":
+		return true, nil
+	case "off", "false", "0", "no":
+		return false, nil
+	default:
+		return false, fmt.Errorf("expected on or off, got %q", value)
+	}
+}
+
+// check returns why the link may not be opened, or nil. deny_host matches
+// the link's host or the HostName ssh_config resolves it to; allow_host has
+// to match the resolved host, so an ssh_config alias can't sneak past it.
+func (p *policy) check(u *url.URL, host, resolvedHost string) error {
+	if slices.Contains(p.disabledSchemes, u.Scheme) {
+		return fmt.Errorf("blocked by policy: %s:// links are disabled", u.Scheme)
+	}
+
+	query := u.Query()
+	if p.remoteCommandOff && (query.Has("cmd") || query.Has("cd")) {
+		return fmt.Errorf("blocked by policy: remote commands are not allowed")
+	}
+	if !p.remoteCommandOptIn && query.Has("cmd") {
+		return fmt.Errorf("blocked by policy: remote commands are off, set allow_remote_command=on in %s to run cmd= links", configPath())
+	}
+	if !p.allowForward && query.Has("forward") {
+		return fmt.Errorf("blocked by policy: port forwarding is not allowed")
+	}
+
+	for _, name := range []string{host, resolvedHost} {
+		if matchesAny(p.denyHosts, name) {
+			return fmt.Errorf("blocked by policy: host %s is denied", name)
+		}
+	}
+	for _, allowlist := range p.allowHosts {
+		if !matchesAny(allowlist, resolvedHost) {
+			return fmt.Errorf("blocked by policy: host %s is not allowed", resolvedHost)
+		}
+	}
+	return nil
+}
+
+// matchesAny matches host names the way DNS compares them: ignoring case
+// and a trailing dot, so DB.INTERNAL. can't slip past *.internal
+func matchesAny(patterns []string, host string) bool {
+	host = normalizeHost(host)
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(normalizeHost(pattern), host); matched {
+			return true
+		}
+	}
+	return false
+}
+
+func normalizeHost(host string) string {
+	return strings.TrimSuffix(strings.ToLower(host), ".")
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeTestFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Remove(path) })
+}
+
+func TestPolicy(t *testing.T) {
+	writeTestFile(t, systemPolicyPath(), "deny_host=*.internal\nallow_host=*.example.com\nallow_host=bastion\nallow_remote_command=off\n")
+	writeTestFile(t, filepath.Join(os.Getenv("HOME"), ".ssh", "config"), "Host jump\n  HostName db.internal\n")
+
+	tests := []struct {
+		link    string
+		blocked string
+	}{
+		{"sshlink://deploy@app.example.com", ""},
+		{"sshlink://bastion:2222", ""},
+		{"sshlink://other.org", "host other.org is not allowed"},
+		{"sshlink://db.internal", "host db.internal is denied"},
+		{"sshlink://DB.INTERNAL", "host DB.INTERNAL is denied"},
+		{"sshlink://db.internal.", "host db.internal. is denied"},
+		{"sshlink://App.Example.COM.", ""},
+		{"sshlink://jump", "host db.internal is denied"},
+		{"sshlink://app.example.com?cmd=uptime", "remote commands are not allowed"},
+		{"sshlink://app.example.com?forward=8080:localhost:80", ""},
+	}
+	for _, tt := range tests {
+		_, err := parseLink(tt.link)
+		if tt.blocked == "" && err != nil {
+			t.Errorf("%s: expected it to open, got %v", tt.link, err)
+		}
+		if tt.blocked != "" && (err == nil || !strings.Contains(err.Error(), tt.blocked)) {
+			t.Errorf("%s: expected %q, got %v", tt.link, tt.blocked, err)
+		}
+	}
+}
+
+func TestPolicyCannotBeLoosened(t *testing.T) {
+	writeTestFile(t, systemPolicyPath(), "allow_host=*.example.com\nallow_forward=off\ndisable_scheme=scplink\n")
+	writeTestFile(t, configPath(), "allow_host=*\nallow_forward=on\ndeny_host=legacy.example.com\n")
+
+	for link, blocked := range map[string]string{
+		"sshlink://other.org":                         "host other.org is not allowed",
+		"sshlink://app.example.com?forward=8080:x:80": "port forwarding is not allowed",
+		"scplink://app.example.com":                   "scplink:// links are disabled",
+		"sshlink://legacy.example.com":                "host legacy.example.com is denied",
+	} {
+		if _, err := parseLink(link); err == nil || !strings.Contains(err.Error(), blocked) {
+			t.Errorf("%s: expected %q, got %v", link, blocked, err)
+		}
+	}
+}
+
+func TestRemoteCommandsNeedOptIn(t *testing.T) {
+	tests := []struct {
+		name    string
+		system  string
+		config  string
+		link    string
+		blocked string
+	}{
+		{name: "Off by default", link: "sshlink://app1?cmd=uptime", blocked: "set allow_remote_command=on"},
+		{name: "Directory without opt-in", link: "sshlink://app1?cd=/srv"},
+		{name: "User opt-in", config: "allow_remote_command=on\n", link: "sshlink://app1?cmd=uptime"},
+		{name: "System opt-in", system: "allow_remote_command=on\n", link: "sshlink://app1?cmd=uptime"},
+		{name: "System off wins", system: "allow_remote_command=off\n", config: "allow_remote_command=on\n", link: "sshlink://app1?cmd=uptime", blocked: "remote commands are not allowed"},
+		{name: "User off blocks directories", config: "allow_remote_command=off\n", link: "sshlink://app1?cd=/srv", blocked: "remote commands are not allowed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			writeTestFile(t, systemPolicyPath(), tt.system)
+			writeTestFile(t, configPath(), tt.config)
+
+			_, err := parseLink(tt.link)
+			if tt.blocked == "" && err != nil {
+				t.Errorf("Expected it to open, got %v", err)
+			}
+			if tt.blocked != "" && (err == nil || !strings.Contains(err.Error(), tt.blocked)) {
+				t.Errorf("Expected %q, got %v", tt.blocked, err)
+			}
+		})
+	}
+}
+
+func TestInvalidPolicyRefusesLinks(t *testing.T) {
+	writeTestFile(t, systemPolicyPath(), "allow_forward=maybe\n")
+
+	if _, err := parseLink("sshlink://app.example.com"); err == nil || !strings.Contains(err.Error(), "invalid policy") {
+		t.Errorf("Expected an invalid policy to refuse links, got %v", err)
+	}
+	if result := checkPolicy(); result.Status != "fail" {
+		t.Errorf("Expected doctor to fail the policy check, got %+v", result)
+	}
+}
+
+func TestInvalidUserPolicyRefusesLinks(t *testing.T) {
+	writeTestFile(t, configPath(), "deny_host *.internal\n")
+
+	_, err := parseLink("sshlink://db.internal")
+	if err == nil || !strings.Contains(err.Error(), "invalid policy") || !strings.Contains(err.Error(), configPath()) {
+		t.Errorf("Expected a malformed user config to refuse links, got %v", err)
+	}
+	if result := checkPolicy(); result.Status != "fail" {
+		t.Errorf("Expected doctor to fail the policy check, got %+v", result)
+	}
+}
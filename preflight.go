@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/icanhazstring/sshlink/logging"
+	"github.com/icanhazstring/sshlink/sshconfig"
+)
+
+// defaultPreflightTimeout bounds the pre-flight dial unless preflight_timeout is set
+const defaultPreflightTimeout = 3 * time.Second
+
+// preflightAddress returns the host:port ssh will connect to, or "" when
+// the connection goes through a jump host and can't be probed directly
+func preflightAddress(t Target, cfg *sshconfig.Config) string {
+	settings := cfg.Resolve(t.Host, t.User)
+	for _, key := range []string{"proxyjump", "proxycommand"} {
+		if value := settings[key]; value != "" && value != "none" {
+			logging.Debugf("Skipping pre-flight for %s, %s is set", t.Host, key)
+			return ""
+		}
+	}
+
+	resolved := resolveTarget(t, cfg)
+	return net.JoinHostPort(resolved.Host, resolved.Port)
+}
+
+// preflightEnabled reports whether to probe before opening a terminal: the
+// link's preflight= parameter wins over the preflight=on config setting
+func preflightEnabled(fromLink string) bool {
+	switch fromLink {
+	case "1", "true", "on":
+		return true
+	case "0", "false", "off":
+		return false
+	}
+	value := readConfigValue("preflight")
+	return value == "on" || value == "true"
+}
+
+func preflightTimeout() time.Duration {
+	if value := readConfigValue("preflight_timeout"); value != "" {
+		if timeout, err := time.ParseDuration(value); err == nil && timeout > 0 {
+			return timeout
+		}
+		logging.Warnf("Ignoring invalid preflight_timeout %q", value)
+	}
+	return defaultPreflightTimeout
+}
+
+// preflight dials addr once so an unreachable host fails fast instead of
+// leaving a terminal hanging on ssh's connect timeout
+func preflight(addr string, timeout time.Duration) error {
+	conn, err := net.DialTimeout("tcp", addr, timeout)
+	if err != nil {
+		return fmt.Errorf("%s is unreachable: %v", addr, err)
+	}
+	return conn.Close()
+}
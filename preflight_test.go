@@ -0,0 +1,71 @@
+package main
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/icanhazstring/sshlink/sshconfig"
+)
+
+func TestPreflight(t *testing.T) {
+	// A local listener stands in for the ssh server
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	addr := listener.Addr().String()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+
+	if err := preflight(addr, time.Second); err != nil {
+		t.Errorf("Expected %s to be reachable, got %v", addr, err)
+	}
+
+	// Once closed, the same port refuses connections
+	listener.Close()
+	start := time.Now()
+	err = preflight(addr, time.Second)
+	if err == nil || !strings.Contains(err.Error(), "unreachable") {
+		t.Errorf("Expected %s to be unreachable, got %v", addr, err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected refused connection to fail fast, took %v", elapsed)
+	}
+}
+
+func TestPreflightAddress(t *testing.T) {
+	cfg, err := sshconfig.Parse(strings.NewReader(`
+Host db
+    HostName 10.0.0.5
+    Port 2222
+Host internal
+    ProxyJump bastion
+`), t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		target Target
+		want   string
+	}{
+		{Target{Host: "db"}, "10.0.0.5:2222"},
+		{Target{Host: "example.com", Port: "2200"}, "example.com:2200"},
+		{Target{Host: "2001:db8::1"}, "[2001:db8::1]:22"},
+		{Target{Host: "internal"}, ""},
+	}
+	for _, tt := range tests {
+		if got := preflightAddress(tt.target, cfg); got != tt.want {
+			t.Errorf("preflightAddress(%v) = %q, want %q", tt.target, got, tt.want)
+		}
+	}
+}
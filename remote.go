@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/icanhazstring/sshlink/terminals"
+)
+
+// remoteCommand builds the command ssh runs on the remote host for links
+// like sshlink://app1?cd=/var/log/app&cmd=tail -f app.log. Only the
+// directory is quoted. cmd is not quoted or checked beyond control
+// characters: the remote shell runs it as written, which is why the policy
+// only allows it after allow_remote_command=on.
+func remoteCommand(dir, cmd string) (string, error) {
+	for _, v := range []string{dir, cmd} {
+		if strings.IndexFunc(v, unicode.IsControl) >= 0 {
+			return "", fmt.Errorf("control characters are not allowed in cd or cmd")
+		}
+	}
+
+	switch {
+	case dir != "" && cmd != "":
+		return fmt.Sprintf("cd %s && %s", terminals.ShellQuote(dir), cmd), nil
+	case dir != "":
+		// Land in the directory with an interactive login shell
+		return fmt.Sprintf(`cd %s && exec "${SHELL:-/bin/sh}" -l`, terminals.ShellQuote(dir)), nil
+	default:
+		return cmd, nil
+	}
+}
+
+// remoteSession runs a remote command over ssh, forcing a tty so that
+// interactive commands like tail -f or top behave as in a normal session
+func remoteSession(s session, dir, cmd string) (session, error) {
+	command, err := remoteCommand(dir, cmd)
+	if err != nil {
+		return session{}, err
+	}
+
+	argv := []string{"ssh", "-t"}
+	if s.target.Port != "" {
+		argv = append(argv, "-p", s.target.Port)
+	}
+	s.command = append(argv, s.target.Destination(), command)
+	return s, nil
+}
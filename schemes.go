@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// linkScheme maps a URL scheme handled by sshlink to the command it opens
+type linkScheme struct {
+	name        string
+	label       string // short name used in tab titles and messages
+	description string
+	// command builds the argv run in the terminal; nil means an
+	// interactive session over the scheme's (or configured) transport
+	command func(t Target) ([]string, error)
+	// transport forces a transport for interactive sessions, e.g. mosh
+	transport string
+}
+
+// linkSchemes is the registry of schemes installed and accepted by handleURL
+var linkSchemes = []linkScheme{
+	{name: "sshlink", label: "ssh", description: "SSH session"},
+	{name: "sftplink", label: "sftp", description: "SFTP file transfer session", command: sftpCommand},
+	{name: "moshlink", label: "mosh", description: "Mosh roaming session", transport: "mosh"},
+	{name: "scplink", label: "scp", description: "Interactive scp/rsync copy", command: copyHelperCommand},
+}
+
+func lookupScheme(name string) (linkScheme, bool) {
+	for _, s := range linkSchemes {
+		if s.name == name {
+			return s, true
+		}
+	}
+	return linkScheme{}, false
+}
+
+// schemeNames lists every registered scheme, e.g. for MimeType= and CFBundleURLSchemes
+func schemeNames() []string {
+	names := make([]string, len(linkSchemes))
+	for i, s := range linkSchemes {
+		names[i] = s.name
+	}
+	return names
+}
+
+// isLinkURL reports whether arg starts with any registered scheme
+func isLinkURL(arg string) bool {
+	for _, s := range linkSchemes {
+		if strings.HasPrefix(arg, s.name+"://") {
+			return true
+		}
+	}
+	return false
+}
+
+func sftpCommand(t Target) ([]string, error) {
+	argv := []string{"sftp"}
+	if t.Port != "" {
+		argv = append(argv, "-P", t.Port)
+	}
+	return append(argv, bracketIPv6(t.Destination())), nil
+}
+
+// copyHelperCommand opens "sshlink copy", which asks for source and
+// destination paths inside the new terminal and then runs rsync or scp
+func copyHelperCommand(t Target) ([]string, error) {
+	execPath, err := os.Executable()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get executable path: %v", err)
+	}
+	argv := []string{execPath, "copy"}
+	if t.Port != "" {
+		argv = append(argv, "-p", t.Port)
+	}
+	return append(argv, t.Destination()), nil
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/icanhazstring/sshlink/logging"
+)
+
+// maxOpenRequestSize bounds the JSON body accepted by POST /open
+const maxOpenRequestSize = 4096
+
+// Timeouts keep slow or stalled clients from holding connections open
+const (
+	serveReadHeaderTimeout = 5 * time.Second
+	serveReadTimeout       = 10 * time.Second
+	serveIdleTimeout       = time.Minute
+)
+
+// launchServer exposes handleURL over localhost HTTP for browsers and
+// kiosks that block custom URL schemes
+type launchServer struct {
+	token    string
+	origins  []string
+	terminal string
+	open     func(urlString, terminalType string) error
+	// openMu serialises links: handleURL sets package-level terminal
+	// state such as the user's shell
+	openMu sync.Mutex
+}
+
+type openRequest struct {
+	Target string `json:"target"`
+}
+
+type openResponse struct {
+	OK     bool   `json:"ok"`
+	Target string `json:"target,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+func runServe(args []string) error {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	addr := fs.String("addr", "127.0.0.1:8722", "Loopback address to listen on")
+	terminal := fs.String("terminal", "terminal", "Terminal to use")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: sshlink serve [options]\n\nOptions:\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if err := requireLoopback(*addr); err != nil {
+		return err
+	}
+
+	token, tokenPath, err := loadOrCreateToken()
+	if err != nil {
+		return err
+	}
+
+	server := &launchServer{
+		token:    token,
+		origins:  readConfigValues("serve_origin"),
+		terminal: resolveTerminalType(*terminal),
+		open:     handleURL,
+	}
+
+	fmt.Printf("🌐 Listening on http://%s (POST /open)\n", *addr)
+	fmt.Printf("🔑 Token: %s\n", tokenPath)
+	if len(server.origins) == 0 {
+		fmt.Println("⚠️  No serve_origin configured, browser requests will be rejected")
+	}
+	httpServer := &http.Server{
+		Addr:              *addr,
+		Handler:           server,
+		ReadHeaderTimeout: serveReadHeaderTimeout,
+		ReadTimeout:       serveReadTimeout,
+		IdleTimeout:       serveIdleTimeout,
+	}
+	return httpServer.ListenAndServe()
+}
+
+func (s *launchServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	origin := r.Header.Get("Origin")
+	if origin != "" {
+		if !s.allowedOrigin(origin) {
+			logging.Debugf("serve: rejected origin %q", origin)
+			writeOpenResponse(w, http.StatusForbidden, openResponse{Error: "origin not allowed"})
+			return
+		}
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Set("Vary", "Origin")
+	}
+
+	if r.URL.Path != "/open" {
+		writeOpenResponse(w, http.StatusNotFound, openResponse{Error: "not found"})
+		return
+	}
+
+	switch r.Method {
+	case http.MethodOptions:
+		// CORS preflight for the Authorization header and JSON body
+		w.Header().Set("Access-Control-Allow-Methods", "POST")
+		w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type")
+		w.WriteHeader(http.StatusNoContent)
+		return
+	case http.MethodPost:
+	default:
+		w.Header().Set("Allow", "POST, OPTIONS")
+		writeOpenResponse(w, http.StatusMethodNotAllowed, openResponse{Error: "method not allowed"})
+		return
+	}
+
+	if !s.authorized(r) {
+		writeOpenResponse(w, http.StatusUnauthorized, openResponse{Error: "invalid token"})
+		return
+	}
+
+	var req openRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxOpenRequestSize)).Decode(&req); err != nil {
+		writeOpenResponse(w, http.StatusBadRequest, openResponse{Error: fmt.Sprintf("invalid JSON: %v", err)})
+		return
+	}
+	if req.Target == "" {
+		writeOpenResponse(w, http.StatusBadRequest, openResponse{Error: "no target specified"})
+		return
+	}
+
+	// Accept both full sshlink:// URLs and bare user@host targets
+	urlString := req.Target
+	if !strings.Contains(urlString, "://") {
+		urlString = "sshlink://" + urlString
+	}
+
+	logging.Debugf("serve: opening %s", urlString)
+	s.openMu.Lock()
+	err := s.open(urlString, s.terminal)
+	s.openMu.Unlock()
+	if err != nil {
+		writeOpenResponse(w, http.StatusUnprocessableEntity, openResponse{Target: req.Target, Error: err.Error()})
+		return
+	}
+	writeOpenResponse(w, http.StatusOK, openResponse{OK: true, Target: req.Target})
+}
+
+func (s *launchServer) allowedOrigin(origin string) bool {
+	for _, allowed := range s.origins {
+		if strings.EqualFold(strings.TrimSuffix(allowed, "/"), origin) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *launchServer) authorized(r *http.Request) bool {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) == 1
+}
+
+func writeOpenResponse(w http.ResponseWriter, status int, resp openResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// requireLoopback refuses to serve on anything reachable from the network
+func requireLoopback(addr string) error {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return fmt.Errorf("invalid address %q: %v", addr, err)
+	}
+	if host == "localhost" {
+		return nil
+	}
+	if ip := net.ParseIP(host); ip == nil || !ip.IsLoopback() {
+		return fmt.Errorf("refusing to listen on non-loopback address %q", addr)
+	}
+	return nil
+}
+
+// loadOrCreateToken returns the per-install API token, generating it on first use
+func loadOrCreateToken() (string, string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", "", fmt.Errorf("failed to get home directory: %v", err)
+	}
+	tokenPath := filepath.Join(homeDir, ".config", "sshlink", "token")
+
+	if content, err := os.ReadFile(tokenPath); err == nil {
+		if token := strings.TrimSpace(string(content)); token != "" {
+			return token, tokenPath, nil
+		}
+	}
+
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", fmt.Errorf("failed to generate token: %v", err)
+	}
+	token := hex.EncodeToString(buf)
+
+	if err := os.MkdirAll(filepath.Dir(tokenPath), 0755); err != nil {
+		return "", "", fmt.Errorf("failed to create config directory: %v", err)
+	}
+	if err := os.WriteFile(tokenPath, []byte(token+"\n"), 0600); err != nil {
+		return "", "", fmt.Errorf("failed to write token: %v", err)
+	}
+	return token, tokenPath, nil
+}
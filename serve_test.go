@@ -0,0 +1,173 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestLaunchServer(t *testing.T) {
+	var opened []string
+	server := &launchServer{
+		token:    "secret",
+		origins:  []string{"https://dashboard.example.com/"},
+		terminal: "terminal",
+		open: func(urlString, terminalType string) error {
+			if strings.Contains(urlString, "fail") {
+				return errors.New("launch failed")
+			}
+			opened = append(opened, urlString)
+			return nil
+		},
+	}
+
+	tests := []struct {
+		name       string
+		method     string
+		origin     string
+		token      string
+		body       string
+		wantStatus int
+		wantOpen   string
+	}{
+		{
+			name:       "Bare target",
+			method:     http.MethodPost,
+			token:      "secret",
+			body:       `{"target": "user@example.com"}`,
+			wantStatus: http.StatusOK,
+			wantOpen:   "sshlink://user@example.com",
+		},
+		{
+			name:       "Full URL from allowed origin",
+			method:     http.MethodPost,
+			origin:     "https://dashboard.example.com",
+			token:      "secret",
+			body:       `{"target": "sshlink://example.com:2222"}`,
+			wantStatus: http.StatusOK,
+			wantOpen:   "sshlink://example.com:2222",
+		},
+		{
+			name:       "Foreign origin",
+			method:     http.MethodPost,
+			origin:     "https://evil.example.com",
+			token:      "secret",
+			body:       `{"target": "example.com"}`,
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "Wrong token",
+			method:     http.MethodPost,
+			token:      "guess",
+			body:       `{"target": "example.com"}`,
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "Missing target",
+			method:     http.MethodPost,
+			token:      "secret",
+			body:       `{}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "Launch error",
+			method:     http.MethodPost,
+			token:      "secret",
+			body:       `{"target": "fail.example.com"}`,
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:       "GET not allowed",
+			method:     http.MethodGet,
+			token:      "secret",
+			wantStatus: http.StatusMethodNotAllowed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opened = nil
+			req := httptest.NewRequest(tt.method, "/open", strings.NewReader(tt.body))
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+			rec := httptest.NewRecorder()
+
+			server.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("Expected status %d, got %d: %s", tt.wantStatus, rec.Code, rec.Body.String())
+			}
+			var resp openResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("Response is not JSON: %v", err)
+			}
+			if resp.OK != (tt.wantStatus == http.StatusOK) {
+				t.Errorf("Expected ok=%v, got %+v", tt.wantStatus == http.StatusOK, resp)
+			}
+			if tt.wantOpen != "" && (len(opened) != 1 || opened[0] != tt.wantOpen) {
+				t.Errorf("Expected %s to be opened, got %v", tt.wantOpen, opened)
+			}
+			if tt.wantOpen == "" && len(opened) != 0 {
+				t.Errorf("Expected nothing to be opened, got %v", opened)
+			}
+		})
+	}
+}
+
+func TestLaunchServerSerialisesLinks(t *testing.T) {
+	var active, overlaps int32
+	server := &launchServer{
+		token: "secret",
+		open: func(urlString, terminalType string) error {
+			if atomic.AddInt32(&active, 1) > 1 {
+				atomic.AddInt32(&overlaps, 1)
+			}
+			time.Sleep(5 * time.Millisecond)
+			atomic.AddInt32(&active, -1)
+			return nil
+		},
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req := httptest.NewRequest(http.MethodPost, "/open", strings.NewReader(`{"target": "example.com"}`))
+			req.Header.Set("Authorization", "Bearer secret")
+			rec := httptest.NewRecorder()
+			server.ServeHTTP(rec, req)
+			if rec.Code != http.StatusOK {
+				t.Errorf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+			}
+		}()
+	}
+	wg.Wait()
+
+	if overlaps != 0 {
+		t.Errorf("Expected links to open one at a time, %d overlapped", overlaps)
+	}
+}
+
+func TestRequireLoopback(t *testing.T) {
+	for addr, ok := range map[string]bool{
+		"127.0.0.1:8722": true,
+		"[::1]:8722":     true,
+		"localhost:8722": true,
+		"0.0.0.0:8722":   false,
+		":8722":          false,
+		"10.0.0.1:8722":  false,
+	} {
+		if err := requireLoopback(addr); (err == nil) != ok {
+			t.Errorf("requireLoopback(%q) = %v", addr, err)
+		}
+	}
+}
@@ -0,0 +1,380 @@
+package sshconfig
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// maxIncludeDepth mirrors the recursion limit OpenSSH applies to Include
+const maxIncludeDepth = 16
+
+// Config is a parsed ssh_config file, with Include directives expanded
+type Config struct {
+	blocks []*block
+}
+
+// Option is a single keyword/argument line of ssh_config
+type Option struct {
+	Key   string // lower-cased keyword, e.g. "hostname"
+	Value string
+}
+
+// Settings are the options that apply to one host, first obtained value wins
+type Settings map[string]string
+
+// block is a run of options guarded by a Host or Match line
+type block struct {
+	hosts    []string    // Host patterns, nil for Match and global blocks
+	criteria [][2]string // Match criteria as (name, argument) pairs
+	matchAll bool        // global options before the first Host/Match
+	inherit  bool        // continues an earlier block's condition after an Include
+	options  []Option
+}
+
+// DefaultPath returns the per-user ssh_config path (~/.ssh/config)
+func DefaultPath() string {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(homeDir, ".ssh", "config")
+}
+
+// Load parses the ssh_config at path. A missing file yields an empty config.
+func Load(path string) (*Config, error) {
+	cfg := &Config{}
+	if path == "" {
+		return cfg, nil
+	}
+
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return cfg, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	cfg, err = Parse(f, filepath.Dir(path))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return cfg, nil
+}
+
+// Parse reads ssh_config content from r. Relative Include paths are resolved
+// against baseDir, which is ~/.ssh for the user config.
+func Parse(r io.Reader, baseDir string) (*Config, error) {
+	cfg := &Config{}
+	global := &block{matchAll: true}
+	cfg.blocks = append(cfg.blocks, global)
+	if err := cfg.parse(r, baseDir, global, 0); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+func (c *Config) parse(r io.Reader, baseDir string, current *block, depth int) error {
+	scanner := bufio.NewScanner(r)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		key, args, err := splitLine(scanner.Text())
+		if err != nil {
+			return fmt.Errorf("line %d: %v", lineNo, err)
+		}
+		if key == "" {
+			continue
+		}
+
+		switch key {
+		case "host":
+			if len(args) == 0 {
+				return fmt.Errorf("line %d: Host requires at least one pattern", lineNo)
+			}
+			current = &block{hosts: args}
+			c.blocks = append(c.blocks, current)
+
+		case "match":
+			criteria, err := parseCriteria(args)
+			if err != nil {
+				return fmt.Errorf("line %d: %v", lineNo, err)
+			}
+			current = &block{criteria: criteria}
+			c.blocks = append(c.blocks, current)
+
+		case "include":
+			if depth >= maxIncludeDepth {
+				return fmt.Errorf("line %d: Include nested too deeply", lineNo)
+			}
+			if err := c.include(args, baseDir, current, depth); err != nil {
+				return fmt.Errorf("line %d: %v", lineNo, err)
+			}
+			// Lines after the Include keep the including block's condition
+			current = current.continuation()
+			c.blocks = append(c.blocks, current)
+
+		default:
+			current.options = append(current.options, Option{Key: key, Value: strings.Join(args, " ")})
+		}
+	}
+	return scanner.Err()
+}
+
+func (c *Config) include(patterns []string, baseDir string, parent *block, depth int) error {
+	for _, pattern := range patterns {
+		if strings.HasPrefix(pattern, "~/") {
+			if homeDir, err := os.UserHomeDir(); err == nil {
+				pattern = filepath.Join(homeDir, pattern[2:])
+			}
+		} else if !filepath.IsAbs(pattern) {
+			pattern = filepath.Join(baseDir, pattern)
+		}
+
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return fmt.Errorf("bad Include pattern %q: %v", pattern, err)
+		}
+		sort.Strings(matches)
+
+		for _, path := range matches {
+			f, err := os.Open(path)
+			if err != nil {
+				return err
+			}
+			// Leading options of the included file share the parent's condition
+			current := parent.continuation()
+			c.blocks = append(c.blocks, current)
+			err = c.parse(f, baseDir, current, depth+1)
+			f.Close()
+			if err != nil {
+				return fmt.Errorf("%s: %v", path, err)
+			}
+		}
+	}
+	return nil
+}
+
+// continuation returns an empty block guarded by the same condition as b
+func (b *block) continuation() *block {
+	return &block{hosts: b.hosts, criteria: b.criteria, matchAll: b.matchAll, inherit: true}
+}
+
+// Resolve returns the settings that apply when connecting to alias as user.
+// An empty user means the local user, as with ssh.
+func (c *Config) Resolve(alias, user string) Settings {
+	settings := Settings{}
+	for _, b := range c.blocks {
+		if !b.matches(alias, user, settings) {
+			continue
+		}
+		for _, opt := range b.options {
+			if _, ok := settings[opt.Key]; !ok {
+				settings[opt.Key] = opt.Value
+			}
+		}
+	}
+
+	if hostname, ok := settings["hostname"]; ok {
+		settings["hostname"] = strings.ReplaceAll(hostname, "%h", alias)
+	}
+	return settings
+}
+
+// HasHost reports whether alias is named by a literal (non-wildcard) Host pattern
+func (c *Config) HasHost(alias string) bool {
+	for _, host := range c.Hosts() {
+		if strings.EqualFold(host, alias) {
+			return true
+		}
+	}
+	return false
+}
+
+// Hosts lists every literal Host alias in file order, without duplicates
+func (c *Config) Hosts() []string {
+	var hosts []string
+	seen := map[string]bool{}
+	for _, b := range c.blocks {
+		if b.inherit {
+			continue
+		}
+		for _, pattern := range b.hosts {
+			if strings.ContainsAny(pattern, "*?!") || seen[pattern] {
+				continue
+			}
+			seen[pattern] = true
+			hosts = append(hosts, pattern)
+		}
+	}
+	return hosts
+}
+
+func (b *block) matches(alias, user string, settings Settings) bool {
+	if b.matchAll {
+		return true
+	}
+	if b.hosts != nil {
+		return matchPatternList(b.hosts, alias)
+	}
+
+	for _, criterion := range b.criteria {
+		name, arg := criterion[0], criterion[1]
+		negate := strings.HasPrefix(name, "!")
+		name = strings.TrimPrefix(name, "!")
+
+		var ok bool
+		switch name {
+		case "all":
+			ok = true
+		case "host":
+			hostname := alias
+			if h, found := settings["hostname"]; found {
+				hostname = strings.ReplaceAll(h, "%h", alias)
+			}
+			ok = matchPatternList(strings.Split(arg, ","), hostname)
+		case "originalhost":
+			ok = matchPatternList(strings.Split(arg, ","), alias)
+		case "user":
+			if u, found := settings["user"]; found {
+				user = u
+			} else if user == "" {
+				user = os.Getenv("USER")
+			}
+			ok = matchPatternList(strings.Split(arg, ","), user)
+		case "localuser":
+			ok = matchPatternList(strings.Split(arg, ","), os.Getenv("USER"))
+		case "final":
+			ok = true
+		default:
+			// canonical, exec and friends can't be evaluated without
+			// running ssh, so treat them as not matching
+			ok = false
+		}
+
+		if ok == negate {
+			return false
+		}
+	}
+	return true
+}
+
+func parseCriteria(args []string) ([][2]string, error) {
+	var criteria [][2]string
+	for i := 0; i < len(args); i++ {
+		name := strings.ToLower(args[i])
+		switch strings.TrimPrefix(name, "!") {
+		case "all", "canonical", "final":
+			criteria = append(criteria, [2]string{name, ""})
+		default:
+			if i+1 >= len(args) {
+				return nil, fmt.Errorf("Match %s requires an argument", name)
+			}
+			criteria = append(criteria, [2]string{name, args[i+1]})
+			i++
+		}
+	}
+	if len(criteria) == 0 {
+		return nil, fmt.Errorf("Match requires at least one criterion")
+	}
+	return criteria, nil
+}
+
+// splitLine tokenises one ssh_config line into a lower-cased keyword and
+// its arguments, accepting both "Key value" and "Key=value" forms
+func splitLine(line string) (string, []string, error) {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return "", nil, nil
+	}
+
+	end := strings.IndexAny(line, " \t=")
+	if end < 0 {
+		return strings.ToLower(line), nil, nil
+	}
+	key := strings.ToLower(line[:end])
+	rest := strings.TrimLeft(line[end:], " \t")
+	rest = strings.TrimPrefix(rest, "=")
+
+	var args []string
+	var current strings.Builder
+	inQuotes, hasToken := false, false
+	for _, r := range rest {
+		switch {
+		case r == '"':
+			inQuotes = !inQuotes
+			hasToken = true
+		case (r == ' ' || r == '\t') && !inQuotes:
+			if hasToken {
+				args = append(args, current.String())
+				current.Reset()
+				hasToken = false
+			}
+		case r == '#' && !inQuotes && !hasToken:
+			// Trailing comment
+			return key, args, nil
+		default:
+			current.WriteRune(r)
+			hasToken = true
+		}
+	}
+	if inQuotes {
+		return "", nil, fmt.Errorf("unterminated quote")
+	}
+	if hasToken {
+		args = append(args, current.String())
+	}
+	return key, args, nil
+}
+
+// matchPatternList implements ssh's pattern-list semantics: at least one
+// pattern must match and no negated (!) pattern may match
+func matchPatternList(patterns []string, value string) bool {
+	value = strings.ToLower(value)
+	matched := false
+	for _, pattern := range patterns {
+		pattern = strings.ToLower(strings.TrimSpace(pattern))
+		if strings.HasPrefix(pattern, "!") {
+			if matchPattern(pattern[1:], value) {
+				return false
+			}
+			continue
+		}
+		if matchPattern(pattern, value) {
+			matched = true
+		}
+	}
+	return matched
+}
+
+// matchPattern matches value against a glob supporting only * and ?
+func matchPattern(pattern, value string) bool {
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case '*':
+			for i := len(value); i >= 0; i-- {
+				if matchPattern(pattern[1:], value[i:]) {
+					return true
+				}
+			}
+			return false
+		case '?':
+			if value == "" {
+				return false
+			}
+			pattern, value = pattern[1:], value[1:]
+		default:
+			if value == "" || pattern[0] != value[0] {
+				return false
+			}
+			pattern, value = pattern[1:], value[1:]
+		}
+	}
+	return value == ""
+}
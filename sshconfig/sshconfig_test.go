@@ -0,0 +1,110 @@
+package sshconfig
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testConfig = `
+# Global defaults
+ServerAliveInterval 30
+
+Host prod-db-1 prod-db-2
+    HostName %h.internal.example.com
+    User admin
+    Port 2222
+
+Host jump
+    HostName=bastion.example.com
+
+Host *.staging !legacy.staging
+    User "deploy user"
+    ProxyJump jump
+
+Match host *.internal.example.com
+    IdentityFile ~/.ssh/internal_ed25519
+
+Include conf.d/*.conf
+
+Host *
+    User fallback
+`
+
+func TestResolve(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "conf.d"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	included := "Host work\n    HostName work.example.com\n    Port 2200\n"
+	if err := os.WriteFile(filepath.Join(dir, "conf.d", "work.conf"), []byte(included), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := Parse(strings.NewReader(testConfig), dir)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	tests := []struct {
+		alias string
+		want  Settings
+	}{
+		{
+			alias: "prod-db-1",
+			want: Settings{
+				"hostname":     "prod-db-1.internal.example.com",
+				"user":         "admin",
+				"port":         "2222",
+				"identityfile": "~/.ssh/internal_ed25519",
+			},
+		},
+		{
+			alias: "jump",
+			want:  Settings{"hostname": "bastion.example.com", "user": "fallback"},
+		},
+		{
+			alias: "app.staging",
+			want:  Settings{"user": "deploy user", "proxyjump": "jump"},
+		},
+		{
+			alias: "legacy.staging",
+			want:  Settings{"user": "fallback", "proxyjump": ""},
+		},
+		{
+			alias: "work",
+			want:  Settings{"hostname": "work.example.com", "port": "2200"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.alias, func(t *testing.T) {
+			got := cfg.Resolve(tt.alias, "")
+			if got["serveraliveinterval"] != "30" {
+				t.Errorf("global option missing, got %v", got)
+			}
+			for key, want := range tt.want {
+				if got[key] != want {
+					t.Errorf("%s: expected %q, got %q", key, want, got[key])
+				}
+			}
+		})
+	}
+
+	wantHosts := []string{"prod-db-1", "prod-db-2", "jump", "work"}
+	if hosts := cfg.Hosts(); strings.Join(hosts, ",") != strings.Join(wantHosts, ",") {
+		t.Errorf("Hosts() = %v, want %v", hosts, wantHosts)
+	}
+	if !cfg.HasHost("PROD-DB-2") || cfg.HasHost("app.staging") {
+		t.Errorf("HasHost did not respect literal aliases")
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, input := range []string{"Host", "Match host", `User "unterminated`} {
+		if _, err := Parse(strings.NewReader(input), t.TempDir()); err == nil {
+			t.Errorf("expected error for %q", input)
+		}
+	}
+}
@@ -0,0 +1,260 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+)
+
+// statusFile is a file created by install
+type statusFile struct {
+	Name   string `json:"name"`
+	Path   string `json:"path"`
+	Exists bool   `json:"exists"`
+}
+
+// installStatus describes what is installed where and whether it still
+// matches the running binary
+type installStatus struct {
+	Version          string            `json:"version"`
+	InstalledVersion string            `json:"installed_version,omitempty"`
+	Running          string            `json:"running"`
+	Installed        string            `json:"installed,omitempty"`
+	DesktopFile      string            `json:"desktop_file,omitempty"`
+	System           bool              `json:"system,omitempty"`
+	Files            []statusFile      `json:"files"`
+	Handlers         map[string]string `json:"handlers"`
+	// Stale explains why the install doesn't match the running binary
+	Stale string `json:"stale,omitempty"`
+}
+
+// installation is what install left for the handler to run
+type installation struct {
+	binary  string // "" when not installed
+	version string // version that installed it, "" where unknown
+	// desktopFile is the Linux desktop entry, system is set when it is
+	// the system-wide one
+	desktopFile string
+	system      *systemLayout
+}
+
+// findInstallation locates the install the handler uses. On Linux the
+// user's own desktop file takes precedence over a system-wide one, as it
+// does when the desktop picks a handler.
+func findInstallation() installation {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return installation{}
+	}
+	switch runtime.GOOS {
+	case "darwin":
+		appPath := filepath.Join(homeDir, "Applications", "SSHLink.app")
+		plist, _ := os.ReadFile(filepath.Join(appPath, "Contents", "Info.plist"))
+		return installation{
+			binary:  filepath.Join(appPath, "Contents", "MacOS", "SSHLink-real"),
+			version: plistVersion(string(plist)),
+		}
+	case "linux":
+		desktopFile := linuxDesktopFile(homeDir)
+		if binary := desktopFileExec(desktopFile); binary != "" {
+			inst := installation{binary: binary, desktopFile: desktopFile}
+			if st, err := loadInstallState(); err == nil {
+				inst.version = st.Version
+			}
+			return inst
+		}
+		if layout, ok := findSystemInstall(); ok {
+			return installation{binary: desktopFileExec(layout.desktopFile()), desktopFile: layout.desktopFile(), system: &layout}
+		}
+	}
+	return installation{}
+}
+
+func linuxDesktopFile(homeDir string) string {
+	return filepath.Join(homeDir, ".local", "share", "applications", "sshlink.desktop")
+}
+
+// desktopFileExec returns the program of the desktop file's Exec= line
+func desktopFileExec(desktopFile string) string {
+	content, err := os.ReadFile(desktopFile)
+	if err != nil {
+		return ""
+	}
+	for _, line := range strings.Split(string(content), "\n") {
+		if value, ok := strings.CutPrefix(strings.TrimSpace(line), "Exec="); ok {
+			return desktopExecPath(value)
+		}
+	}
+	return ""
+}
+
+var plistVersionPattern = regexp.MustCompile(`<key>CFBundleVersion</key>\s*<string>([^<]*)</string>`)
+
+func plistVersion(plist string) string {
+	if match := plistVersionPattern.FindStringSubmatch(plist); match != nil {
+		return match[1]
+	}
+	return ""
+}
+
+// staleReason compares the installed binary with the running one. The
+// macOS bundle holds a copy, so after an upgrade it keeps running the old
+// version; on Linux the desktop file may point at a binary that moved.
+func staleReason(installed, installedVersion, running string) string {
+	if installed == "" {
+		return ""
+	}
+	if installedVersion != "" && installedVersion != version {
+		return fmt.Sprintf("installed by version %s, running %s", installedVersion, version)
+	}
+	if installed == running {
+		return ""
+	}
+
+	installedContent, err := os.ReadFile(installed)
+	if err != nil {
+		return fmt.Sprintf("%s is missing", installed)
+	}
+	runningContent, err := os.ReadFile(running)
+	if err != nil {
+		return ""
+	}
+	if checksum(installedContent) != checksum(runningContent) {
+		return fmt.Sprintf("%s differs from the running binary %s", installed, running)
+	}
+	return ""
+}
+
+func collectStatus() (*installStatus, error) {
+	running, err := os.Executable()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get executable path: %v", err)
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get home directory: %v", err)
+	}
+
+	status := &installStatus{Version: version, Running: running, Handlers: map[string]string{}}
+	inst := findInstallation()
+	status.Installed, status.InstalledVersion = inst.binary, inst.version
+	status.DesktopFile, status.System = inst.desktopFile, inst.system != nil
+	status.Stale = staleReason(status.Installed, status.InstalledVersion, running)
+
+	var files [][2]string
+	switch runtime.GOOS {
+	case "darwin":
+		appPath := filepath.Join(homeDir, "Applications", "SSHLink.app")
+		files = [][2]string{
+			{"App bundle", appPath},
+			{"Info.plist", filepath.Join(appPath, "Contents", "Info.plist")},
+			{"Preferences", filepath.Join(homeDir, "Library", "Preferences", "com.icanhazstring.sshlink.plist")},
+		}
+		status.Handlers = macOSHandlers(appPath)
+	case "linux":
+		systemInstall, ok := findSystemInstall()
+		if !ok {
+			systemInstall = systemLayout{prefix: systemPrefixes[0]}
+		}
+		files = [][2]string{
+			{"Desktop file", linuxDesktopFile(homeDir)},
+			{"System desktop", systemInstall.desktopFile()},
+			{"Install state", installStatePath()},
+			{"System config", systemConfigPath()},
+			{"System policy", systemPolicyPath()},
+		}
+		for _, scheme := range schemeNames() {
+			status.Handlers[scheme] = queryDefaultHandler(scheme)
+		}
+	}
+	files = append(files, [2]string{"Config", configPath()})
+
+	for _, file := range files {
+		_, err := os.Stat(file[1])
+		status.Files = append(status.Files, statusFile{Name: file[0], Path: file[1], Exists: err == nil})
+	}
+	return status, nil
+}
+
+// macOSHandlers reports the schemes Launch Services knows SSHLink.app for
+func macOSHandlers(appPath string) map[string]string {
+	handlers := map[string]string{}
+	output, err := commandOutput(lsregisterPath, "-dump")
+	if err != nil || !strings.Contains(string(output), appPath) {
+		return handlers
+	}
+	for _, scheme := range schemeNames() {
+		if strings.Contains(string(output), scheme+":") {
+			handlers[scheme] = "SSHLink.app"
+		}
+	}
+	return handlers
+}
+
+func runStatus(args []string) error {
+	fs := flag.NewFlagSet("status", flag.ExitOnError)
+	asJSON := fs.Bool("json", false, "Print the status as JSON")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: sshlink status [-json]\n\nOptions:\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	status, err := collectStatus()
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(status)
+	}
+
+	fmt.Printf("Version:   %s\n", status.Version)
+	fmt.Printf("Running:   %s\n", status.Running)
+	if status.Installed != "" {
+		installed := status.Installed
+		if status.InstalledVersion != "" {
+			installed += " (version " + status.InstalledVersion + ")"
+		}
+		if status.System {
+			installed += " for all users"
+		}
+		fmt.Printf("Installed: %s\n", installed)
+		if status.DesktopFile != "" {
+			fmt.Printf("Handler:   %s\n", status.DesktopFile)
+		}
+	} else {
+		fmt.Println("Installed: not installed")
+	}
+
+	fmt.Println("\nFiles:")
+	for _, file := range status.Files {
+		icon := "✅"
+		if !file.Exists {
+			icon = "➖"
+		}
+		fmt.Printf("  %s %-14s %s\n", icon, file.Name, file.Path)
+	}
+
+	fmt.Println("\nHandlers:")
+	for _, scheme := range schemeNames() {
+		handler := status.Handlers[scheme]
+		if handler == "" {
+			handler = "none"
+		}
+		fmt.Printf("  %s:// → %s\n", scheme, handler)
+	}
+
+	if status.Stale != "" {
+		fmt.Printf("\n⚠️  Stale install: %s\n", status.Stale)
+		fmt.Println(`   Fix: run "sshlink upgrade"`)
+	}
+	return nil
+}
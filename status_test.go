@@ -0,0 +1,84 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestStaleReason(t *testing.T) {
+	dir := t.TempDir()
+	running := filepath.Join(dir, "sshlink")
+	copied := filepath.Join(dir, "SSHLink-real")
+	os.WriteFile(running, []byte("new build"), 0755)
+	os.WriteFile(copied, []byte("new build"), 0755)
+
+	tests := []struct {
+		name             string
+		installed        string
+		installedVersion string
+		stale            string
+	}{
+		{name: "Not installed", installed: "", stale: ""},
+		{name: "Same binary", installed: running, installedVersion: version, stale: ""},
+		{name: "Identical copy", installed: copied, stale: ""},
+		{name: "Older version", installed: copied, installedVersion: "0.9.0", stale: "installed by version 0.9.0"},
+		{name: "Missing binary", installed: filepath.Join(dir, "moved"), stale: "is missing"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := staleReason(tt.installed, tt.installedVersion, running)
+			if (tt.stale == "") != (got == "") || !strings.Contains(got, tt.stale) {
+				t.Errorf("Expected %q, got %q", tt.stale, got)
+			}
+		})
+	}
+
+	os.WriteFile(copied, []byte("old build"), 0755)
+	if got := staleReason(copied, "", running); !strings.Contains(got, "differs from the running binary") {
+		t.Errorf("Expected a changed copy to be stale, got %q", got)
+	}
+}
+
+func TestPlistVersion(t *testing.T) {
+	plist := "<dict>\n\t<key>CFBundleVersion</key>\n\t<string>1.4.0</string>\n</dict>"
+	if got := plistVersion(plist); got != "1.4.0" {
+		t.Errorf("Expected 1.4.0, got %q", got)
+	}
+	if got := plistVersion("<dict></dict>"); got != "" {
+		t.Errorf("Expected no version, got %q", got)
+	}
+}
+
+func TestFindSystemInstallation(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("desktop entries are Linux only")
+	}
+	layout := systemLayout{prefix: systemPrefixes[1]}
+	binary := filepath.Join(layout.prefix, "bin", "sshlink")
+	writeTestFile(t, binary, "old build")
+	writeTestFile(t, layout.desktopFile(), "[Desktop Entry]\nExec="+binary+" -terminal=gnome-terminal %u\n")
+
+	inst := findInstallation()
+	if inst.binary != binary || inst.system == nil || inst.system.prefix != layout.prefix {
+		t.Fatalf("Expected the system install under %s, got %+v", layout.prefix, inst)
+	}
+
+	status, err := collectStatus()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !status.System || status.DesktopFile != layout.desktopFile() || !strings.Contains(status.Stale, "differs from the running binary") {
+		t.Errorf("Expected a stale system install, got %+v", status)
+	}
+
+	// The user's own install takes precedence
+	homeDir, _ := os.UserHomeDir()
+	running, _ := os.Executable()
+	writeTestFile(t, linuxDesktopFile(homeDir), "[Desktop Entry]\nExec="+running+" %u\n")
+	if inst := findInstallation(); inst.binary != running || inst.system != nil {
+		t.Errorf("Expected the user install, got %+v", inst)
+	}
+}
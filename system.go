@@ -0,0 +1,238 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"github.com/icanhazstring/sshlink/terminals"
+)
+
+// systemPolicyTemplate is written on the first system install. An existing
+// policy is never overwritten.
+const systemPolicyTemplate = `# sshlink system policy. Users can add restrictions in their own config,
+# but can't lift the ones set here.
+#
+# deny_host=*.internal.example.com
+# allow_host=*.example.com
+# disable_scheme=scplink
+# cmd= links are refused unless a user's config or this file has
+# allow_remote_command=on; off here also refuses cd= and can't be undone.
+# allow_remote_command=off
+# allow_forward=off
+`
+
+// systemLayout locates the files of a system-wide install. destDir is
+// prepended to every path written, as with make install DESTDIR=, while
+// the paths inside the files stay relative to the real root.
+type systemLayout struct {
+	prefix  string
+	destDir string
+}
+
+func (l systemLayout) binary() string {
+	return filepath.Join(l.prefix, "bin", "sshlink")
+}
+
+func (l systemLayout) applicationsDir() string {
+	return filepath.Join(l.prefix, "share", "applications")
+}
+
+func (l systemLayout) desktopFile() string {
+	return filepath.Join(l.applicationsDir(), "sshlink.desktop")
+}
+
+// mimeApps is /etc/xdg/mimeapps.list, next to the system config
+func (l systemLayout) mimeApps() string {
+	return filepath.Join(filepath.Dir(systemConfigDir), "xdg", "mimeapps.list")
+}
+
+// staged returns where path is written, below destDir
+func (l systemLayout) staged(path string) string {
+	if l.destDir == "" {
+		return path
+	}
+	return filepath.Join(l.destDir, path)
+}
+
+// systemPrefixes are searched, in order, for a system-wide install
+var systemPrefixes = []string{"/usr/local", "/usr"}
+
+// findSystemInstall returns the layout of the system-wide install, if any
+func findSystemInstall() (systemLayout, bool) {
+	for _, prefix := range systemPrefixes {
+		layout := systemLayout{prefix: prefix}
+		if _, err := os.Stat(layout.desktopFile()); err == nil {
+			return layout, true
+		}
+	}
+	return systemLayout{}, false
+}
+
+func runInstall(args []string) error {
+	fs := flag.NewFlagSet("install", flag.ExitOnError)
+	terminal := fs.String("terminal", "terminal", "Terminal to use")
+	system := fs.Bool("system", false, "Install for all users (Linux, needs root)")
+	prefix := fs.String("prefix", "/usr/local", "Installation prefix for -system")
+	destDir := fs.String("destdir", os.Getenv("DESTDIR"), "Staging directory prepended to -system paths")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: sshlink install [options]\n\nOptions:\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if !*system {
+		return installHandler(*terminal)
+	}
+	if runtime.GOOS != "linux" {
+		return fmt.Errorf("system install is only supported on Linux")
+	}
+	term, err := terminals.CreateTerminal(*terminal)
+	if err != nil {
+		return err
+	}
+	return installSystem(systemLayout{prefix: *prefix, destDir: *destDir}, term.Name())
+}
+
+func runUninstall(args []string) error {
+	fs := flag.NewFlagSet("uninstall", flag.ExitOnError)
+	system := fs.Bool("system", false, "Remove the system-wide install")
+	prefix := fs.String("prefix", "/usr/local", "Installation prefix used for -system")
+	destDir := fs.String("destdir", os.Getenv("DESTDIR"), "Staging directory used for -system")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: sshlink uninstall [options]\n\nOptions:\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if !*system {
+		return uninstallHandler()
+	}
+	return uninstallSystem(systemLayout{prefix: *prefix, destDir: *destDir})
+}
+
+// installSystem installs the binary, desktop file, system config, policy
+// and default handlers for every user
+func installSystem(layout systemLayout, terminalName string) error {
+	fmt.Printf("📦 Installing sshlink for all users (prefix %s)...\n", layout.prefix)
+
+	execPath, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to get executable path: %v", err)
+	}
+	binary := layout.staged(layout.binary())
+	if binary != execPath {
+		content, err := os.ReadFile(execPath)
+		if err != nil {
+			return fmt.Errorf("failed to read executable: %v", err)
+		}
+		if err := writeSystemFile(binary, content, 0755); err != nil {
+			return err
+		}
+	}
+
+	desktopContent, err := desktopFileContent(layout.binary(), terminalName)
+	if err != nil {
+		return err
+	}
+	if err := writeSystemFile(layout.staged(layout.desktopFile()), desktopContent, 0644); err != nil {
+		return err
+	}
+
+	configFile := layout.staged(systemConfigPath())
+	existing, err := os.ReadFile(configFile)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read %s: %v", configFile, err)
+	}
+	if err := writeSystemFile(configFile, []byte(setConfigValue(string(existing), "terminal", terminalName)), 0644); err != nil {
+		return err
+	}
+
+	policyFile := layout.staged(systemPolicyPath())
+	if _, err := os.Stat(policyFile); os.IsNotExist(err) {
+		if err := writeSystemFile(policyFile, []byte(systemPolicyTemplate), 0644); err != nil {
+			return err
+		}
+	} else {
+		fmt.Printf("ℹ️  Keeping existing policy: %s\n", policyFile)
+	}
+
+	if err := setSystemHandlers(layout, "sshlink.desktop"); err != nil {
+		return err
+	}
+
+	// A staged tree is indexed when the package is installed
+	if layout.destDir == "" {
+		if err := runCommand("update-desktop-database", layout.applicationsDir()); err != nil {
+			fmt.Printf("⚠️  Warning: Could not update desktop database: %v\n", err)
+		}
+	}
+
+	fmt.Println("✅ SSHLink installed for all users")
+	fmt.Printf("   Policy: %s\n", systemPolicyPath())
+	fmt.Println("   Users' own handler choices still take precedence over the system default")
+	return nil
+}
+
+func uninstallSystem(layout systemLayout) error {
+	fmt.Printf("🗑️  Removing system-wide sshlink (prefix %s)...\n", layout.prefix)
+
+	for _, path := range []string{
+		layout.staged(layout.desktopFile()),
+		layout.staged(layout.binary()),
+	} {
+		if err := os.Remove(path); err == nil {
+			fmt.Printf("🗑️  Removed: %s\n", path)
+		} else if !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove %s: %v", path, err)
+		}
+	}
+
+	if err := setSystemHandlers(layout, ""); err != nil {
+		return err
+	}
+	if layout.destDir == "" {
+		if err := runCommand("update-desktop-database", layout.applicationsDir()); err != nil {
+			fmt.Printf("⚠️  Warning: Could not update desktop database: %v\n", err)
+		}
+	}
+
+	fmt.Printf("ℹ️  Kept %s, remove it if the config and policy are no longer needed\n", layout.staged(systemConfigDir))
+	fmt.Println("✅ SSHLink removed for all users")
+	return nil
+}
+
+// setSystemHandlers sets (or with "" removes) the default handler of every
+// scheme in the system-wide mimeapps.list
+func setSystemHandlers(layout systemLayout, handler string) error {
+	mimeApps := layout.staged(layout.mimeApps())
+	content, err := os.ReadFile(mimeApps)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read %s: %v", mimeApps, err)
+	}
+	if os.IsNotExist(err) && handler == "" {
+		return nil
+	}
+
+	edited := string(content)
+	for _, scheme := range schemeNames() {
+		edited = setMimeDefault(edited, "x-scheme-handler/"+scheme, handler)
+	}
+	if edited == string(content) {
+		return nil
+	}
+	return writeSystemFile(mimeApps, []byte(edited), 0644)
+}
+
+func writeSystemFile(path string, content []byte, mode os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %v", filepath.Dir(path), err)
+	}
+	if err := writeFileAtomic(path, content, mode); err != nil {
+		return fmt.Errorf("failed to write %s: %v", path, err)
+	}
+	fmt.Printf("📄 Wrote: %s\n", path)
+	return nil
+}
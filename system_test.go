@@ -0,0 +1,69 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/icanhazstring/sshlink/terminals"
+)
+
+func TestInstallSystem(t *testing.T) {
+	runner := &terminals.RecordingRunner{}
+	originalRunner := terminals.DefaultRunner
+	defer func() { terminals.DefaultRunner = originalRunner }()
+	terminals.DefaultRunner = runner
+
+	destDir := t.TempDir()
+	layout := systemLayout{prefix: "/usr", destDir: destDir}
+	staged := func(path string) string { return filepath.Join(destDir, path) }
+
+	writeTestFile(t, staged(systemPolicyPath()), "deny_host=*.internal\n")
+	writeTestFile(t, staged(layout.mimeApps()), "[Default Applications]\ntext/html=firefox.desktop\n")
+
+	if err := installSystem(layout, "gnome-terminal"); err != nil {
+		t.Fatalf("installSystem failed: %v", err)
+	}
+
+	if info, err := os.Stat(staged("/usr/bin/sshlink")); err != nil || info.Mode().Perm() != 0755 {
+		t.Errorf("Expected an executable binary, got %v, %v", info, err)
+	}
+	desktop, _ := os.ReadFile(staged("/usr/share/applications/sshlink.desktop"))
+	if !strings.Contains(string(desktop), "Exec=/usr/bin/sshlink -terminal=gnome-terminal %u") {
+		t.Errorf("Expected the desktop file to run the installed binary, got:\n%s", desktop)
+	}
+	config, _ := os.ReadFile(staged(systemConfigPath()))
+	if string(config) != "terminal=gnome-terminal\n" {
+		t.Errorf("Unexpected system config: %q", config)
+	}
+	policy, _ := os.ReadFile(staged(systemPolicyPath()))
+	if string(policy) != "deny_host=*.internal\n" {
+		t.Errorf("Expected the existing policy to be kept, got %q", policy)
+	}
+	mimeApps, _ := os.ReadFile(staged(layout.mimeApps()))
+	for _, scheme := range schemeNames() {
+		if !strings.Contains(string(mimeApps), "x-scheme-handler/"+scheme+"=sshlink.desktop\n") {
+			t.Errorf("Expected %s to be registered in:\n%s", scheme, mimeApps)
+		}
+	}
+	if len(runner.Invocations) != 0 {
+		t.Errorf("Expected no commands for a staged install, got %v", runner.Commands())
+	}
+
+	if err := uninstallSystem(layout); err != nil {
+		t.Fatalf("uninstallSystem failed: %v", err)
+	}
+	for _, path := range []string{"/usr/bin/sshlink", "/usr/share/applications/sshlink.desktop"} {
+		if _, err := os.Stat(staged(path)); !os.IsNotExist(err) {
+			t.Errorf("Expected %s to be removed", path)
+		}
+	}
+	mimeApps, _ = os.ReadFile(staged(layout.mimeApps()))
+	if string(mimeApps) != "[Default Applications]\ntext/html=firefox.desktop\n" {
+		t.Errorf("Expected the other defaults to be kept, got:\n%s", mimeApps)
+	}
+	if _, err := os.Stat(staged(systemConfigPath())); err != nil {
+		t.Errorf("Expected the system config to be kept: %v", err)
+	}
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/icanhazstring/sshlink/logging"
+	"github.com/icanhazstring/sshlink/sshconfig"
+)
+
+// Target is the ssh destination carried by an sshlink:// URL
+type Target struct {
+	User string
+	Host string
+	Port string
+}
+
+func parseTarget(u *url.URL) Target {
+	t := Target{Host: u.Hostname(), Port: u.Port()}
+	if u.User != nil {
+		t.User = u.User.Username()
+	}
+	return t
+}
+
+// String renders the target for display, e.g. user@host:2222. ssh doesn't
+// accept this form, use sshCommand to connect.
+func (t Target) String() string {
+	host := t.Host
+	if t.Port != "" {
+		host = net.JoinHostPort(t.Host, t.Port)
+	}
+	if t.User != "" {
+		return t.User + "@" + host
+	}
+	return host
+}
+
+// Destination renders user@host as ssh expects it, without the port
+func (t Target) Destination() string {
+	if t.User != "" {
+		return t.User + "@" + t.Host
+	}
+	return t.Host
+}
+
+// bracketIPv6 encloses an IPv6 host in [], as scp, rsync and sftp need to
+// tell it apart from a path: user@[2001:db8::1]
+func bracketIPv6(destination string) string {
+	if at := strings.LastIndex(destination, "@"); strings.Contains(destination[at+1:], ":") {
+		return destination[:at+1] + "[" + destination[at+1:] + "]"
+	}
+	return destination
+}
+
+// sshCommand returns the argv of an interactive ssh session to the target
+func (t Target) sshCommand() []string {
+	argv := []string{"ssh"}
+	if t.Port != "" {
+		argv = append(argv, "-p", t.Port)
+	}
+	return append(argv, t.Destination())
+}
+
+var (
+	validUser = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9._-]*$`)
+	validHost = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9._:%-]*$`)
+	validName = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9._@:/-]*$`)
+)
+
+// validateTarget rejects anything that could be mistaken for an option or
+// shell syntax by ssh and the terminal backends, e.g. sshlink://-oProxyCommand=...
+func validateTarget(t Target) error {
+	if !validHost.MatchString(t.Host) {
+		return fmt.Errorf("invalid host: %q", t.Host)
+	}
+	if t.User != "" && !validUser.MatchString(t.User) {
+		return fmt.Errorf("invalid user: %q", t.User)
+	}
+	if t.Port != "" {
+		if port, err := strconv.Atoi(t.Port); err != nil || port < 1 || port > 65535 {
+			return fmt.Errorf("invalid port: %q", t.Port)
+		}
+	}
+	return nil
+}
+
+// validateName checks a container, pod, namespace or context name; empty is allowed
+func validateName(kind, value string) error {
+	if value != "" && !validName.MatchString(value) {
+		return fmt.Errorf("invalid %s: %q", kind, value)
+	}
+	return nil
+}
+
+// loadSSHConfig parses ~/.ssh/config, returning an empty config on failure
+func loadSSHConfig() *sshconfig.Config {
+	cfg, err := sshconfig.Load(sshconfig.DefaultPath())
+	if err != nil {
+		logging.Warnf("Failed to parse ssh config: %v", err)
+		fmt.Printf("⚠️  Warning: ignoring ~/.ssh/config: %v\n", err)
+		return &sshconfig.Config{}
+	}
+	return cfg
+}
+
+// applySSHConfig reports how ssh will resolve the target and drops the
+// link's user or port where the user's ssh_config already defines one for
+// the alias, so links never override local settings
+func applySSHConfig(t Target, cfg *sshconfig.Config) Target {
+	if !cfg.HasHost(t.Host) {
+		if looksLikeAlias(t.Host) {
+			fmt.Printf("⚠️  Warning: %s is not a Host alias in ~/.ssh/config, relying on DNS\n", t.Host)
+		}
+		return t
+	}
+
+	settings := cfg.Resolve(t.Host, t.User)
+	if t.User != "" && settings["user"] != "" && settings["user"] != t.User {
+		fmt.Printf("ℹ️  Keeping User %s from ~/.ssh/config instead of %s\n", settings["user"], t.User)
+		t.User = ""
+	}
+	if t.Port != "" && settings["port"] != "" && settings["port"] != t.Port {
+		fmt.Printf("ℹ️  Keeping Port %s from ~/.ssh/config instead of %s\n", settings["port"], t.Port)
+		t.Port = ""
+	}
+
+	resolved := resolveTarget(t, cfg)
+	fmt.Printf("🔎 %s resolves to %s via ~/.ssh/config\n", t.Host, resolved)
+	return t
+}
+
+// resolveTarget returns the effective HostName, User and Port ssh will use
+func resolveTarget(t Target, cfg *sshconfig.Config) Target {
+	settings := cfg.Resolve(t.Host, t.User)
+	resolved := Target{Host: t.Host, User: t.User, Port: t.Port}
+	if hostname := settings["hostname"]; hostname != "" {
+		resolved.Host = hostname
+	}
+	if resolved.User == "" {
+		resolved.User = settings["user"]
+	}
+	if resolved.Port == "" {
+		resolved.Port = settings["port"]
+	}
+	if resolved.Port == "" {
+		resolved.Port = "22"
+	}
+	return resolved
+}
+
+// looksLikeAlias reports whether host is a bare name rather than an IP or FQDN
+func looksLikeAlias(host string) bool {
+	return net.ParseIP(host) == nil && !strings.Contains(host, ".") && host != "localhost"
+}
@@ -1,9 +1,5 @@
 package terminals
 
-import (
-	"os/exec"
-)
-
 type GenericTerminal struct {
 	BaseTerminal
 	args []string
@@ -16,10 +12,11 @@ func NewGenericTerminal(name string, args []string) Terminal {
 	}
 }
 
-func (t *GenericTerminal) Open(host string) error {
-	var args []string
-	args = append(t.args, "ssh", host)
+func (t *GenericTerminal) Exec(command []string) error {
+	return runAll(t.runner(), t.Plan(command))
+}
 
-	cmd := exec.Command(t.Name_, args...)
-	return cmd.Start()
+func (t *GenericTerminal) Plan(command []string) []Invocation {
+	args := append(append([]string{}, t.args...), command...)
+	return []Invocation{{Name: t.Name_, Args: args}}
 }
@@ -0,0 +1,30 @@
+package terminals
+
+// Invocation is one process a backend starts to open a session. Backends
+// build their invocations with Plan so they can be shown without running.
+type Invocation struct {
+	Name  string
+	Args  []string
+	Stdin string // fed to the process when not empty
+	// Wait runs the process to completion instead of starting it
+	Wait bool
+}
+
+// String renders the invocation as a shell command line
+func (i Invocation) String() string {
+	s := ShellJoin(append([]string{i.Name}, i.Args...))
+	if i.Stdin != "" {
+		s += " <<< " + ShellQuote(i.Stdin)
+	}
+	return s
+}
+
+// runAll runs invocations in order with runner, stopping at the first failure
+func runAll(runner Runner, invocations []Invocation) error {
+	for _, i := range invocations {
+		if err := runner.Run(i); err != nil {
+			return err
+		}
+	}
+	return nil
+}
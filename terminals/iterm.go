@@ -2,7 +2,6 @@ package terminals
 
 import (
 	"fmt"
-	"os/exec"
 )
 
 type ITerm struct {
@@ -15,14 +14,32 @@ func NewITerm() Terminal {
 	}
 }
 
-func (t *ITerm) Open(host string) error {
-	script := fmt.Sprintf(`tell application "iTerm"
+func (t *ITerm) Exec(command []string) error {
+	return runAll(t.runner(), t.Plan(command))
+}
+
+func (t *ITerm) Plan(command []string) []Invocation {
+	script := itermScript("iTerm", command, t.title, t.profile)
+	return []Invocation{{Name: "osascript", Args: []string{"-e", script}, Wait: true}}
+}
+
+// itermScript builds the AppleScript shared by iTerm and iTerm2
+func itermScript(app string, command []string, title, profile string) string {
+	window := "create window with default profile"
+	if profile != "" {
+		window = fmt.Sprintf("create window with profile %s", AppleScriptString(profile))
+	}
+
+	name := ""
+	if title != "" {
+		name = fmt.Sprintf("\n\t\tset name to %s", AppleScriptString(title))
+	}
+
+	return fmt.Sprintf(`tell application "%s"
 	activate
-	create window with default profile
-	tell current session of current window
-		write text "ssh %s"
+	%s
+	tell current session of current window%s
+		write text %s
 	end tell
-end tell`, host)
-	cmd := exec.Command("osascript", "-e", script)
-	return cmd.Run()
+end tell`, app, window, name, AppleScriptString(ShellJoin(command)))
 }
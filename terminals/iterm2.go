@@ -1,10 +1,5 @@
 package terminals
 
-import (
-	"fmt"
-	"os/exec"
-)
-
 type ITerm2 struct {
 	BaseTerminal
 }
@@ -15,14 +10,11 @@ func NewITerm2() Terminal {
 	}
 }
 
-func (t *ITerm2) Open(host string) error {
-	script := fmt.Sprintf(`tell application "iTerm2"
-	activate
-	create window with default profile
-	tell current session of current window
-		write text "ssh %s"
-	end tell
-end tell`, host)
-	cmd := exec.Command("osascript", "-e", script)
-	return cmd.Run()
+func (t *ITerm2) Exec(command []string) error {
+	return runAll(t.runner(), t.Plan(command))
+}
+
+func (t *ITerm2) Plan(command []string) []Invocation {
+	script := itermScript("iTerm2", command, t.title, t.profile)
+	return []Invocation{{Name: "osascript", Args: []string{"-e", script}, Wait: true}}
 }
@@ -17,11 +17,21 @@ func NewLinuxTerminal(name string, shell string) Terminal {
 	}
 }
 
-func (t *LinuxTerminal) Open(host string) error {
+func (t *LinuxTerminal) Exec(command []string) error {
+	return runAll(t.runner(), t.Plan(command))
+}
+
+func (t *LinuxTerminal) Plan(command []string) []Invocation {
 	// For gnome-terminal: gnome-terminal --tab -- /bin/bash -c "ssh host; exec /bin/bash"
-	args := []string{"--tab", "--", t.shell, "-c", fmt.Sprintf("ssh %s; exec %s", host, t.shell)}
-	cmd := exec.Command(t.Name_, args...)
-	return cmd.Start()
+	args := []string{"--tab"}
+	if t.title != "" {
+		args = append(args, "--title="+t.title)
+	}
+	if t.profile != "" {
+		args = append(args, "--profile="+t.profile)
+	}
+	args = append(args, "--", t.shell, "-c", fmt.Sprintf("%s; exec %s", ShellJoin(command), t.shell))
+	return []Invocation{{Name: t.Name_, Args: args}}
 }
 
 func (t *LinuxTerminal) IsAvailable() bool {
@@ -2,7 +2,6 @@ package terminals
 
 import (
 	"fmt"
-	"os/exec"
 )
 
 type MacOSTerminal struct {
@@ -15,11 +14,20 @@ func NewMacOSTerminal() Terminal {
 	}
 }
 
-func (t *MacOSTerminal) Open(host string) error {
+func (t *MacOSTerminal) Exec(command []string) error {
+	return runAll(t.runner(), t.Plan(command))
+}
+
+func (t *MacOSTerminal) Plan(command []string) []Invocation {
 	script := fmt.Sprintf(`tell application "Terminal"
 	activate
-	do script "ssh %s"
-end tell`, host)
-	cmd := exec.Command("osascript", "-e", script)
-	return cmd.Run()
+	set newTab to do script %s`, AppleScriptString(ShellJoin(command)))
+	if t.title != "" {
+		script += fmt.Sprintf("\n\tset custom title of newTab to %s", AppleScriptString(t.title))
+	}
+	if t.profile != "" {
+		script += fmt.Sprintf("\n\tset current settings of newTab to settings set %s", AppleScriptString(t.profile))
+	}
+	script += "\nend tell"
+	return []Invocation{{Name: "osascript", Args: []string{"-e", script}, Wait: true}}
 }
@@ -0,0 +1,28 @@
+package terminals
+
+import "strings"
+
+// ShellJoin renders argv as a POSIX shell command line, quoting
+// arguments that contain anything beyond a conservative safe set
+func ShellJoin(argv []string) string {
+	quoted := make([]string, len(argv))
+	for i, arg := range argv {
+		quoted[i] = ShellQuote(arg)
+	}
+	return strings.Join(quoted, " ")
+}
+
+// ShellQuote quotes arg for POSIX sh, leaving it as is when it is safe
+func ShellQuote(arg string) string {
+	if arg != "" && strings.Trim(arg, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789@%+=:,./_-") == "" {
+		return arg
+	}
+	return "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+}
+
+// AppleScriptString quotes s as an AppleScript string literal
+func AppleScriptString(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	return `"` + s + `"`
+}
@@ -0,0 +1,87 @@
+package terminals
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+	"sync"
+)
+
+// Runner runs the processes behind terminal backends and install helpers.
+// Tests replace it with a RecordingRunner to check the exact argv.
+type Runner interface {
+	// Run starts the invocation, waiting for it to exit if i.Wait is set
+	Run(i Invocation) error
+	// Output runs the invocation to completion and returns its stdout.
+	// Errors include whatever the process wrote to stderr.
+	Output(i Invocation) ([]byte, error)
+}
+
+// DefaultRunner is used by backends without a Runner of their own and by
+// the install helpers in the main package
+var DefaultRunner Runner = ExecRunner{}
+
+// ExecRunner runs invocations with os/exec
+type ExecRunner struct{}
+
+func (ExecRunner) Run(i Invocation) error {
+	cmd := i.command()
+	if i.Wait {
+		return cmd.Run()
+	}
+	return cmd.Start()
+}
+
+func (ExecRunner) Output(i Invocation) ([]byte, error) {
+	var stderr bytes.Buffer
+	cmd := i.command()
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil && stderr.Len() > 0 {
+		return output, fmt.Errorf("%v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return output, err
+}
+
+func (i Invocation) command() *exec.Cmd {
+	cmd := exec.Command(i.Name, i.Args...)
+	if i.Stdin != "" {
+		cmd.Stdin = strings.NewReader(i.Stdin)
+	}
+	return cmd
+}
+
+// RecordingRunner records invocations instead of running them
+type RecordingRunner struct {
+	mu          sync.Mutex
+	Invocations []Invocation
+	// Respond, if set, supplies the output and error for an invocation
+	Respond func(i Invocation) ([]byte, error)
+}
+
+func (r *RecordingRunner) Run(i Invocation) error {
+	_, err := r.Output(i)
+	return err
+}
+
+func (r *RecordingRunner) Output(i Invocation) ([]byte, error) {
+	r.mu.Lock()
+	r.Invocations = append(r.Invocations, i)
+	r.mu.Unlock()
+	if r.Respond != nil {
+		return r.Respond(i)
+	}
+	return nil, nil
+}
+
+// Commands renders the recorded invocations, one command line each
+func (r *RecordingRunner) Commands() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	commands := make([]string, len(r.Invocations))
+	for i, invocation := range r.Invocations {
+		commands[i] = invocation.String()
+	}
+	return commands
+}
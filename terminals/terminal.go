@@ -1,18 +1,34 @@
 package terminals
 
-import (
-	"os/exec"
-)
+import "os/exec"
 
 // Terminal interface defines the contract for all terminal implementations
 type Terminal interface {
-	Open(host string) error
+	// Exec runs command, e.g. ssh, sftp or mosh, in a new tab or window
+	Exec(command []string) error
+	// Plan returns what Exec would run for command, without running it
+	Plan(command []string) []Invocation
 	Name() string
 	IsAvailable() bool
+	// SetTitle sets the tab/window title used by the next Exec, where supported
+	SetTitle(title string)
+	// SetProfile selects a colour profile for the next Exec, where supported
+	SetProfile(profile string)
 }
 
 type BaseTerminal struct {
 	Name_ string
+	// Runner runs the backend's commands, nil means DefaultRunner
+	Runner  Runner
+	title   string
+	profile string
+}
+
+func (b BaseTerminal) runner() Runner {
+	if b.Runner != nil {
+		return b.Runner
+	}
+	return DefaultRunner
 }
 
 func (b BaseTerminal) Name() string {
@@ -24,3 +40,11 @@ func (b BaseTerminal) IsAvailable() bool {
 	_, err := exec.LookPath(b.Name_)
 	return err == nil
 }
+
+func (b *BaseTerminal) SetTitle(title string) {
+	b.title = title
+}
+
+func (b *BaseTerminal) SetProfile(profile string) {
+	b.profile = profile
+}
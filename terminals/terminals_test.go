@@ -0,0 +1,113 @@
+package terminals
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "Rewrite the golden files in testdata")
+
+func TestBackendsGolden(t *testing.T) {
+	backends := []struct {
+		golden string
+		create func() Terminal
+	}{
+		{"terminal", NewMacOSTerminal},
+		{"iterm", NewITerm},
+		{"iterm2", NewITerm2},
+		{"warp", NewWarp},
+		{"gnome-terminal", func() Terminal { return NewLinuxTerminal("gnome-terminal", "/bin/zsh") }},
+		{"generic", func() Terminal { return NewGenericTerminal("cmd", []string{"/c", "start", "cmd", "/k"}) }},
+	}
+
+	for _, backend := range backends {
+		t.Run(backend.golden, func(t *testing.T) {
+			var out strings.Builder
+
+			// Plain ssh session, as opened for sshlink:// links
+			terminal, runner := withRecorder(backend.create())
+			if err := terminal.Exec([]string{"ssh", "-p", "2222", "deploy@example.com"}); err != nil {
+				t.Fatalf("Exec failed: %v", err)
+			}
+			writeCommands(&out, "Exec ssh -p 2222 deploy@example.com", runner)
+
+			// Exec with title, profile and arguments that need quoting
+			terminal, runner = withRecorder(backend.create())
+			terminal.SetTitle(`ssh: it's "prod"`)
+			terminal.SetProfile("Production")
+			if err := terminal.Exec([]string{"ssh", "-t", "app1", `cd '/var/log/my app' && exec "${SHELL:-/bin/sh}" -l`}); err != nil {
+				t.Fatalf("Exec failed: %v", err)
+			}
+			writeCommands(&out, "Exec with title and profile", runner)
+
+			// Plan must describe exactly what Exec runs
+			if planned := terminal.Plan([]string{"ssh", "-t", "app1", `cd '/var/log/my app' && exec "${SHELL:-/bin/sh}" -l`}); len(planned) != len(runner.Invocations) {
+				t.Errorf("Plan returned %d invocations, Exec ran %d", len(planned), len(runner.Invocations))
+			}
+
+			compareGolden(t, filepath.Join("testdata", backend.golden+".golden"), out.String())
+		})
+	}
+}
+
+func withRecorder(terminal Terminal) (Terminal, *RecordingRunner) {
+	runner := &RecordingRunner{}
+	switch t := terminal.(type) {
+	case *MacOSTerminal:
+		t.Runner = runner
+	case *ITerm:
+		t.Runner = runner
+	case *ITerm2:
+		t.Runner = runner
+	case *Warp:
+		t.Runner = runner
+	case *LinuxTerminal:
+		t.Runner = runner
+	case *GenericTerminal:
+		t.Runner = runner
+	}
+	return terminal, runner
+}
+
+func writeCommands(out *strings.Builder, heading string, runner *RecordingRunner) {
+	out.WriteString("# " + heading + "\n")
+	for _, command := range runner.Commands() {
+		out.WriteString(command + "\n")
+	}
+	out.WriteString("\n")
+}
+
+func compareGolden(t *testing.T, path, got string) {
+	t.Helper()
+	if *update {
+		if err := os.WriteFile(path, []byte(got), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	expected, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Missing golden file, run go test ./terminals -update: %v", err)
+	}
+	if string(expected) != got {
+		t.Errorf("Commands differ from %s\n--- expected\n%s\n--- got\n%s", path, expected, got)
+	}
+}
+
+func TestShellQuote(t *testing.T) {
+	tests := map[string]string{
+		"deploy@example.com:2222": "deploy@example.com:2222",
+		"":                        "''",
+		"my app":                  "'my app'",
+		"it's":                    `'it'\''s'`,
+		"$HOME":                   "'$HOME'",
+	}
+	for arg, expected := range tests {
+		if got := ShellQuote(arg); got != expected {
+			t.Errorf("ShellQuote(%q) = %s, expected %s", arg, got, expected)
+		}
+	}
+}
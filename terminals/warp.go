@@ -2,8 +2,6 @@ package terminals
 
 import (
 	"fmt"
-	"os/exec"
-	"strings"
 )
 
 // Warp implementation
@@ -17,22 +15,26 @@ func NewWarp() Terminal {
 	}
 }
 
-func (t *Warp) Open(host string) error {
-	// Warp doesn't have good AppleScript automation, so we'll copy the command
-	// to clipboard and open Warp - user can just paste with Cmd+V
-	sshCommand := fmt.Sprintf("ssh %s", host)
+func (t *Warp) Exec(command []string) error {
+	plan := t.Plan(command)
+	copyCommand, openWarp := plan[0], plan[1]
 
 	// Copy SSH command to clipboard
-	cmd := exec.Command("pbcopy")
-	cmd.Stdin = strings.NewReader(sshCommand)
-	if err := cmd.Run(); err != nil {
+	if err := t.runner().Run(copyCommand); err != nil {
 		fmt.Printf("⚠️  Could not copy to clipboard: %v\n", err)
 	} else {
-		fmt.Printf("📋 Copied to clipboard: %s\n", sshCommand)
+		fmt.Printf("📋 Copied to clipboard: %s\n", copyCommand.Stdin)
 		fmt.Println("💡 Paste with Cmd+V in Warp terminal")
 	}
 
-	// Open Warp
-	cmd = exec.Command("open", "-a", "Warp")
-	return cmd.Run()
+	return t.runner().Run(openWarp)
+}
+
+func (t *Warp) Plan(command []string) []Invocation {
+	// Warp doesn't have good AppleScript automation, so we'll copy the command
+	// to clipboard and open Warp - user can just paste with Cmd+V
+	return []Invocation{
+		{Name: "pbcopy", Stdin: ShellJoin(command), Wait: true},
+		{Name: "open", Args: []string{"-a", "Warp"}, Wait: true},
+	}
 }
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/icanhazstring/sshlink/logging"
+	"github.com/icanhazstring/sshlink/terminals"
+)
+
+// lookPath finds local binaries; tests replace it to control fallbacks
+var lookPath = exec.LookPath
+
+var transports = map[string]bool{"ssh": true, "mosh": true, "et": true}
+
+// transportFor picks the transport for a host: the link's transport=
+// parameter, then a "transport=<pattern>:<name>" config entry, then a
+// plain "transport=<name>" entry, defaulting to ssh
+func transportFor(host, fromLink string) (string, error) {
+	transport := fromLink
+	if transport == "" {
+		transport = readPatternValue("transport", host)
+	}
+	if transport == "" {
+		for _, value := range readConfigValues("transport") {
+			if !strings.Contains(value, ":") {
+				transport = strings.TrimSpace(value)
+				break
+			}
+		}
+	}
+	if transport == "" {
+		return "ssh", nil
+	}
+	if !transports[transport] {
+		return "", fmt.Errorf("unsupported transport: %s", transport)
+	}
+	return transport, nil
+}
+
+// transportCommand builds the command for a mosh or Eternal Terminal
+// session. It returns nil, meaning plain ssh, when the client isn't
+// installed locally, and otherwise falls back to ssh if the client fails
+// on the remote side (e.g. no mosh-server there).
+func transportCommand(transport string, t Target) []string {
+	if _, err := lookPath(transport); err != nil {
+		fmt.Printf("⚠️  %s is not installed, falling back to ssh\n", transport)
+		logging.Debugf("%s not found: %v", transport, err)
+		return nil
+	}
+
+	var argv []string
+	switch transport {
+	case "mosh":
+		argv = []string{"mosh"}
+		if t.Port != "" {
+			argv = append(argv, "--ssh=ssh -p "+t.Port)
+		}
+	case "et":
+		if t.Port != "" {
+			fmt.Printf("ℹ️  et ignores the link's port %s, set Port in ~/.ssh/config instead\n", t.Port)
+		}
+		argv = []string{"et"}
+	}
+	argv = append(argv, t.Destination())
+
+	script := fmt.Sprintf("%s || { echo 'sshlink: %s failed, falling back to ssh'; exec %s; }",
+		terminals.ShellJoin(argv), transport, terminals.ShellJoin(t.sshCommand()))
+	return []string{"sh", "-c", script}
+}
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/icanhazstring/sshlink/logging"
+)
+
+// validForward matches ssh -L specs: [bind_address:]port:host:hostport
+var validForward = regexp.MustCompile(`^(?:([A-Za-z0-9._-]+|\[[0-9A-Fa-f:.]+\]):)?(\d{1,5}):([A-Za-z0-9._-]+|\[[0-9A-Fa-f:.]+\]):(\d{1,5})$`)
+
+// loopbackBinds are the bind addresses a forward may name
+var loopbackBinds = []string{"localhost", "127.0.0.1", "[::1]"}
+
+// tunnelState is recorded for every background tunnel in the state directory
+type tunnelState struct {
+	PID      int      `json:"pid"`
+	Target   string   `json:"target"`
+	Forwards []string `json:"forwards"`
+	// Command is the argv started, used to tell the tunnel from a later
+	// process that reuses its pid
+	Command []string  `json:"command"`
+	Started time.Time `json:"started"`
+}
+
+// tunnelSession turns an ssh session into one that only forwards ports,
+// e.g. sshlink://bastion?forward=5432:db.internal:5432
+func tunnelSession(s session, forwards []string, background bool) (session, error) {
+	argv := []string{"ssh", "-N", "-o", "ExitOnForwardFailure=yes"}
+	if background {
+		// Nobody can answer a password prompt for a background tunnel
+		argv = append(argv, "-o", "BatchMode=yes")
+	}
+	for _, forward := range forwards {
+		match := validForward.FindStringSubmatch(forward)
+		if match == nil {
+			return session{}, fmt.Errorf("invalid forward: %q (expected [bind:]port:host:hostport)", forward)
+		}
+		// A link must not expose the forwarded service to the network
+		if match[1] != "" && !slices.Contains(loopbackBinds, match[1]) {
+			return session{}, fmt.Errorf("invalid forward: %q binds to %s, only localhost, 127.0.0.1 or [::1] are allowed", forward, match[1])
+		}
+		argv = append(argv, "-L", forward)
+	}
+	if s.target.Port != "" {
+		argv = append(argv, "-p", s.target.Port)
+	}
+	argv = append(argv, s.target.Destination())
+
+	s.label = "tunnel"
+	s.command = argv
+	s.background = background
+	return s, nil
+}
+
+func tunnelsDir() string {
+	return filepath.Join(stateDir(), "tunnels")
+}
+
+// startTunnel runs the tunnel's ssh without a terminal and records its pid
+func startTunnel(s session) error {
+	cmd := exec.Command(s.command[0], s.command[1:]...)
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start tunnel: %v", err)
+	}
+
+	state := tunnelState{
+		PID:     cmd.Process.Pid,
+		Target:  s.name,
+		Command: s.command,
+		Started: time.Now(),
+	}
+	for i, arg := range s.command {
+		if arg == "-L" && i+1 < len(s.command) {
+			state.Forwards = append(state.Forwards, s.command[i+1])
+		}
+	}
+	if err := saveTunnel(state); err != nil {
+		// An untracked tunnel couldn't be stopped with sshlink tunnels stop
+		cmd.Process.Kill()
+		return err
+	}
+
+	logging.Debugf("Started tunnel pid %d: %q", state.PID, s.command)
+	fmt.Printf("🔌 Tunnel to %s running in background (pid %d)\n", s.name, state.PID)
+	for _, forward := range state.Forwards {
+		fmt.Printf("   -L %s\n", forward)
+	}
+	fmt.Println("   Stop it with: sshlink tunnels stop", state.PID)
+	return cmd.Process.Release()
+}
+
+func tunnelStatePath(pid int) string {
+	return filepath.Join(tunnelsDir(), fmt.Sprintf("%d.json", pid))
+}
+
+func saveTunnel(state tunnelState) error {
+	if err := os.MkdirAll(tunnelsDir(), 0700); err != nil {
+		return fmt.Errorf("failed to create tunnel state directory: %v", err)
+	}
+	content, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(tunnelStatePath(state.PID), content, 0600); err != nil {
+		return fmt.Errorf("failed to record tunnel: %v", err)
+	}
+	return nil
+}
+
+// loadTunnels returns the recorded tunnels that are still running,
+// removing the state of any that have exited
+func loadTunnels() ([]tunnelState, error) {
+	entries, err := os.ReadDir(tunnelsDir())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var tunnels []tunnelState
+	for _, entry := range entries {
+		path := filepath.Join(tunnelsDir(), entry.Name())
+		content, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		var state tunnelState
+		if err := json.Unmarshal(content, &state); err != nil || !state.running() {
+			os.Remove(path)
+			continue
+		}
+		tunnels = append(tunnels, state)
+	}
+
+	sort.Slice(tunnels, func(i, j int) bool { return tunnels[i].Started.Before(tunnels[j].Started) })
+	return tunnels, nil
+}
+
+// running reports whether the tunnel's pid is still the ssh it started.
+// Once the tunnel exits its pid can be reused by an unrelated process.
+func (t tunnelState) running() bool {
+	if len(t.Command) == 0 {
+		return false // recorded before the command was kept, can't be told apart
+	}
+	process, err := os.FindProcess(t.PID)
+	if err != nil || process.Signal(syscall.Signal(0)) != nil {
+		return false
+	}
+	command, err := processCommand(t.PID)
+	if err != nil {
+		logging.Debugf("Cannot read the command of pid %d: %v", t.PID, err)
+		return false
+	}
+	return command == strings.Join(t.Command, " ")
+}
+
+// processCommand returns the command line of pid, arguments joined by spaces
+func processCommand(pid int) (string, error) {
+	if runtime.GOOS == "linux" {
+		cmdline, err := os.ReadFile(fmt.Sprintf("/proc/%d/cmdline", pid))
+		if err != nil {
+			return "", err
+		}
+		return strings.Join(strings.Split(strings.TrimSuffix(string(cmdline), "\x00"), "\x00"), " "), nil
+	}
+	output, err := exec.Command("ps", "-o", "command=", "-p", strconv.Itoa(pid)).Output()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+func runTunnels(args []string) error {
+	usage := func() {
+		fmt.Fprintf(os.Stderr, "Usage: sshlink tunnels list\n       sshlink tunnels stop <pid|all>\n")
+	}
+	if len(args) == 0 {
+		usage()
+		return fmt.Errorf("expected list or stop")
+	}
+
+	tunnels, err := loadTunnels()
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "list":
+		fs := flag.NewFlagSet("tunnels list", flag.ExitOnError)
+		fs.Parse(args[1:])
+		if len(tunnels) == 0 {
+			fmt.Println("No tunnels running")
+			return nil
+		}
+		for _, t := range tunnels {
+			fmt.Printf("%-8d %-30s %v  (since %s)\n", t.PID, t.Target, t.Forwards, t.Started.Format(time.DateTime))
+		}
+		return nil
+
+	case "stop":
+		if len(args) != 2 {
+			usage()
+			return fmt.Errorf("expected a pid or all")
+		}
+		stopped := 0
+		for _, t := range tunnels {
+			if args[1] != "all" && args[1] != strconv.Itoa(t.PID) {
+				continue
+			}
+			// loadTunnels only returned tunnels whose pid is still our ssh
+			if process, err := os.FindProcess(t.PID); err == nil {
+				if err := process.Signal(syscall.SIGTERM); err != nil {
+					return fmt.Errorf("failed to stop tunnel %d: %v", t.PID, err)
+				}
+			}
+			os.Remove(tunnelStatePath(t.PID))
+			fmt.Printf("🛑 Stopped tunnel %d to %s\n", t.PID, t.Target)
+			stopped++
+		}
+		if stopped == 0 && args[1] != "all" {
+			return fmt.Errorf("no running tunnel with pid %s", args[1])
+		}
+		return nil
+
+	default:
+		usage()
+		return fmt.Errorf("unknown tunnels command: %s", args[0])
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestTunnelState(t *testing.T) {
+	defer os.RemoveAll(tunnelsDir())
+
+	shell, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip("sh not available")
+	}
+	// Stands in for ssh, the forward is passed as an ignored argument
+	s := session{name: "bastion", command: []string{shell, "-c", "sleep 30 & wait", "-L", "5432:db:5432"}}
+	if err := startTunnel(s); err != nil {
+		t.Fatalf("startTunnel failed: %v", err)
+	}
+
+	// A pid reused by an unrelated process isn't a tunnel
+	reused := tunnelState{PID: os.Getpid(), Target: "old", Command: []string{"ssh", "-N", "old"}, Started: time.Now()}
+	if err := saveTunnel(reused); err != nil {
+		t.Fatal(err)
+	}
+	// State from before the command was recorded can't be verified
+	legacy := tunnelState{PID: os.Getpid() + 1, Target: "legacy", Started: time.Now()}
+	if err := saveTunnel(legacy); err != nil {
+		t.Fatal(err)
+	}
+
+	tunnels, err := loadTunnels()
+	if err != nil {
+		t.Fatalf("loadTunnels failed: %v", err)
+	}
+	if len(tunnels) != 1 || tunnels[0].Target != "bastion" || len(tunnels[0].Forwards) != 1 || tunnels[0].Forwards[0] != "5432:db:5432" {
+		t.Fatalf("Expected only the started tunnel, got %+v", tunnels)
+	}
+	for _, pid := range []int{reused.PID, legacy.PID} {
+		if _, err := os.Stat(tunnelStatePath(pid)); !os.IsNotExist(err) {
+			t.Errorf("Expected the state of pid %d to be removed", pid)
+		}
+	}
+
+	if err := runTunnels([]string{"list"}); err != nil {
+		t.Errorf("tunnels list failed: %v", err)
+	}
+	if err := runTunnels([]string{"stop", strconv.Itoa(os.Getpid())}); err == nil {
+		t.Errorf("Expected stopping a pid that isn't a tunnel to fail")
+	}
+
+	pid := tunnels[0].PID
+	if err := runTunnels([]string{"stop", strconv.Itoa(pid)}); err != nil {
+		t.Fatalf("tunnels stop failed: %v", err)
+	}
+	if _, err := os.Stat(tunnelStatePath(pid)); !os.IsNotExist(err) {
+		t.Errorf("Expected the tunnel state to be removed")
+	}
+	process, _ := os.FindProcess(pid)
+	state, err := process.Wait()
+	if err != nil || state.Success() {
+		t.Errorf("Expected the tunnel to be terminated, got %v, %v", state, err)
+	}
+}
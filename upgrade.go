@@ -0,0 +1,173 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/icanhazstring/sshlink/terminals"
+)
+
+func runUpgrade(args []string) error {
+	return upgradeInstall("upgrade", args, false)
+}
+
+func runReinstall(args []string) error {
+	return upgradeInstall("reinstall", args, true)
+}
+
+// upgradeInstall points an existing install at the running binary. Only
+// the app bundle or desktop entry is refreshed, the config and preferences
+// are kept as they are.
+func upgradeInstall(name string, args []string, force bool) error {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	fs.BoolVar(&force, "force", force, "Refresh even when the install matches the running binary")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: sshlink %s [options]\n\nOptions:\n", name)
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	running, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to get executable path: %v", err)
+	}
+	inst := findInstallation()
+	if inst.binary == "" {
+		return fmt.Errorf(`sshlink is not installed, run "sshlink -install" first`)
+	}
+
+	reason := staleReason(inst.binary, inst.version, running)
+	if reason == "" && !force {
+		fmt.Printf("✅ SSHLink is up to date (version %s)\n", version)
+		return nil
+	}
+	if reason != "" {
+		fmt.Printf("🔄 Refreshing stale install: %s\n", reason)
+	}
+
+	switch runtime.GOOS {
+	case "darwin":
+		return upgradeMacOS(running)
+	case "linux":
+		if inst.system != nil {
+			return upgradeSystem(*inst.system)
+		}
+		return upgradeLinux(running)
+	default:
+		return fmt.Errorf("upgrade not supported on %s", runtime.GOOS)
+	}
+}
+
+func upgradeLinux(running string) error {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return fmt.Errorf("failed to get home directory: %v", err)
+	}
+
+	terminals.SetUserShell(readShellPreference())
+	terminal, err := terminals.CreateTerminal(resolveTerminalType("terminal"))
+	if err != nil {
+		return fmt.Errorf("no usable terminal in %s: %v", configPath(), err)
+	}
+
+	st, err := loadInstallState()
+	if err != nil {
+		return err
+	}
+	desktopFile := linuxDesktopFile(homeDir)
+	// An install from before install state was kept created the file
+	if _, recorded := st.Backups[desktopFile]; !recorded {
+		st.Backups[desktopFile] = nil
+	}
+
+	desktopContent, err := desktopFileContent(running, terminal.Name())
+	if err != nil {
+		return err
+	}
+	changed, err := st.writeFile(desktopFile, desktopContent, 0755)
+	if err != nil {
+		return fmt.Errorf("failed to update desktop file: %v", err)
+	}
+	if changed {
+		fmt.Printf("📄 Updated desktop file: %s\n", desktopFile)
+		if err := runCommand("update-desktop-database", filepath.Dir(desktopFile)); err != nil {
+			fmt.Printf("⚠️  Warning: Could not update desktop database: %v\n", err)
+		}
+	}
+
+	st.Version = version
+	if err := st.save(); err != nil {
+		return fmt.Errorf("failed to save install state: %v", err)
+	}
+
+	fmt.Printf("✅ SSHLink upgraded to version %s\n", version)
+	fmt.Printf("   Executable: %s\n", running)
+	fmt.Printf("   Config kept: %s\n", configPath())
+	return nil
+}
+
+// upgradeSystem reinstalls a system-wide install from the running binary,
+// keeping its terminal. It needs the same permissions as install -system.
+func upgradeSystem(layout systemLayout) error {
+	terminalName := "terminal"
+	entries, _ := parseConfigFile(systemConfigPath())
+	for _, entry := range entries {
+		if entry.key == "terminal" {
+			terminalName = entry.value
+			break
+		}
+	}
+	terminal, err := terminals.CreateTerminal(terminalName)
+	if err != nil {
+		return fmt.Errorf("no usable terminal in %s: %v", systemConfigPath(), err)
+	}
+
+	if err := installSystem(layout, terminal.Name()); err != nil {
+		if strings.Contains(err.Error(), "permission denied") {
+			return fmt.Errorf("%v (upgrading the install for all users needs root: sudo sshlink upgrade)", err)
+		}
+		return err
+	}
+	return nil
+}
+
+func upgradeMacOS(running string) error {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return fmt.Errorf("failed to get home directory: %v", err)
+	}
+	appPath := filepath.Join(homeDir, "Applications", "SSHLink.app")
+	contentsPath := filepath.Join(appPath, "Contents")
+	macOSPath := filepath.Join(contentsPath, "MacOS")
+
+	// Replace the copied binary in one step, a link opened meanwhile runs
+	// either the old or the new one
+	content, err := os.ReadFile(running)
+	if err != nil {
+		return fmt.Errorf("failed to read executable: %v", err)
+	}
+	if err := writeFileAtomic(filepath.Join(macOSPath, "SSHLink-real"), content, 0755); err != nil {
+		return fmt.Errorf("failed to copy executable: %v", err)
+	}
+
+	if err := compileWrapper(filepath.Join(macOSPath, "SSHLink"), filepath.Join(contentsPath, "Resources")); err != nil {
+		return err
+	}
+	if err := writeFileAtomic(filepath.Join(contentsPath, "Info.plist"), []byte(infoPlist()), 0644); err != nil {
+		return fmt.Errorf("failed to update Info.plist: %v", err)
+	}
+
+	fmt.Println("🔄 Registering with macOS Launch Services...")
+	if err := runCommand(lsregisterPath, "-f", appPath); err != nil {
+		return fmt.Errorf("failed to register app with Launch Services: %v", err)
+	}
+
+	fmt.Printf("✅ SSHLink upgraded to version %s\n", version)
+	fmt.Printf("   App bundle: %s\n", appPath)
+	fmt.Println("   Preferences kept")
+	return nil
+}
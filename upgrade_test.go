@@ -0,0 +1,107 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/icanhazstring/sshlink/terminals"
+)
+
+func TestUpgradeLinux(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("desktop entries are Linux only")
+	}
+	runner := &terminals.RecordingRunner{}
+	originalRunner := terminals.DefaultRunner
+	defer func() { terminals.DefaultRunner = originalRunner }()
+	terminals.DefaultRunner = runner
+
+	homeDir, _ := os.UserHomeDir()
+	desktopFile := linuxDesktopFile(homeDir)
+	oldBinary := filepath.Join(t.TempDir(), "sshlink")
+	writeTestFile(t, desktopFile, "[Desktop Entry]\nExec="+oldBinary+" -terminal=gnome-terminal %u\n")
+	config := "terminal=gnome-terminal\nshell=/bin/zsh\nprofile=prod-*:Production\n"
+	writeTestFile(t, configPath(), config)
+	defer os.Remove(installStatePath())
+
+	if err := runUpgrade(nil); err != nil {
+		t.Fatalf("upgrade failed: %v", err)
+	}
+
+	running, _ := os.Executable()
+	if got := desktopFileExec(desktopFile); got != running {
+		t.Errorf("Expected the desktop file to run %s, got %s", running, got)
+	}
+	if content, _ := os.ReadFile(configPath()); string(content) != config {
+		t.Errorf("Expected the config to be kept, got %q", content)
+	}
+	st, err := loadInstallState()
+	if err != nil || st.Version != version {
+		t.Errorf("Expected the install state to record version %s, got %+v, %v", version, st, err)
+	}
+	if backup, ok := st.Backups[desktopFile]; !ok || backup != nil {
+		t.Errorf("Expected uninstall to remove the desktop file, got backup %v", backup)
+	}
+
+	runner.Invocations = nil
+	if err := runUpgrade(nil); err != nil {
+		t.Fatalf("second upgrade failed: %v", err)
+	}
+	if len(runner.Invocations) != 0 {
+		t.Errorf("Expected an up to date install to be left alone, got %v", runner.Commands())
+	}
+	if err := runReinstall(nil); err != nil {
+		t.Errorf("reinstall failed: %v", err)
+	}
+}
+
+func TestUpgradeNotInstalled(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("desktop entries are Linux only")
+	}
+	if err := runUpgrade(nil); err == nil || !strings.Contains(err.Error(), "not installed") {
+		t.Errorf("Expected an error without an install, got %v", err)
+	}
+}
+
+func TestUpgradeSystemInstall(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("desktop entries are Linux only")
+	}
+	runner := &terminals.RecordingRunner{}
+	originalRunner := terminals.DefaultRunner
+	defer func() { terminals.DefaultRunner = originalRunner }()
+	terminals.DefaultRunner = runner
+
+	layout := systemLayout{prefix: systemPrefixes[0]}
+	writeTestFile(t, layout.binary(), "old build")
+	writeTestFile(t, layout.desktopFile(), "[Desktop Entry]\nExec="+layout.binary()+" -terminal=gnome-terminal %u\n")
+	config := "# managed by configuration management\nterminal=gnome-terminal\n"
+	writeTestFile(t, systemConfigPath(), config)
+	defer os.Remove(systemPolicyPath())
+	defer os.Remove(layout.mimeApps())
+
+	if err := runUpgrade(nil); err != nil {
+		t.Fatalf("upgrade failed: %v", err)
+	}
+
+	running, _ := os.Executable()
+	installed, _ := os.ReadFile(layout.binary())
+	current, _ := os.ReadFile(running)
+	if checksum(installed) != checksum(current) {
+		t.Errorf("Expected %s to be replaced by the running binary", layout.binary())
+	}
+	if content, _ := os.ReadFile(systemConfigPath()); string(content) != config {
+		t.Errorf("Expected the system config to be kept, got %q", content)
+	}
+	homeDir, _ := os.UserHomeDir()
+	if _, err := os.Stat(linuxDesktopFile(homeDir)); !os.IsNotExist(err) {
+		t.Errorf("Expected no per-user desktop file to be created")
+	}
+	if inst := findInstallation(); staleReason(inst.binary, inst.version, running) != "" {
+		t.Errorf("Expected the system install to be up to date")
+	}
+}